@@ -1,13 +1,15 @@
 module github.com/rgonomic/rgo
 
-go 1.15
+go 1.18
 
 require (
 	github.com/google/licensecheck v0.0.0-20200805042302-c54f297c3b57
 	github.com/pkg/diff v0.0.0-20200914180035-5b29258ca4f7
 	golang.org/x/mod v0.6.0-dev.0.20220106191415-9b9b3d81d5e3
-	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
 	golang.org/x/tools v0.1.10
+)
+
+require (
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
 	golang.org/x/xerrors v0.0.0-20220517211312-f3a8303e98df // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
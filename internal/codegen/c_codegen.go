@@ -51,10 +51,125 @@ int getListElementIndex(SEXP list, const char *str) {
 		}
 	}
 	return index;
-}{{range $func := .Funcs}}{{$params := varsOf $func.Signature.Params}}
+}{{if .NeedFrames}}
 
-SEXP {{snake $func.Func.Name}}({{c $params}}) {
-	return Wrapped_{{$func.Func.Name}}({{names false $params}});
+// Needed for packing data.frame values.
+void R_setDataFrame(SEXP p, int nrow) {
+	SEXP rownames = PROTECT(allocVector(INTSXP, 2));
+	INTEGER(rownames)[0] = NA_INTEGER;
+	INTEGER(rownames)[1] = -nrow;
+	setAttrib(p, R_RowNamesSymbol, rownames);
+	setAttrib(p, R_ClassSymbol, mkString("data.frame"));
+	UNPROTECT(1);
+}{{end}}{{if .NeedHandles}}
+
+// Needed for releasing handles to Go values.
+static void R_finalizeHandle(SEXP p) {
+	uintptr_t h = (uintptr_t)R_ExternalPtrAddr(p);
+	if (h != 0) {
+		releaseHandle(h);
+	}
+	R_ClearExternalPtr(p);
+}
+
+// Needed for packing handles to Go values.
+SEXP R_makeHandle(uintptr_t h, const char *cls) {
+	SEXP p = PROTECT(R_MakeExternalPtr((void*)h, install(cls), R_NilValue));
+	R_RegisterCFinalizerEx(p, R_finalizeHandle, TRUE);
+	SEXP class = PROTECT(allocVector(STRSXP, 2));
+	SET_STRING_ELT(class, 0, mkChar(cls));
+	SET_STRING_ELT(class, 1, mkChar("rgo_handle"));
+	setAttrib(p, R_ClassSymbol, class);
+	UNPROTECT(2);
+	return p;
+}
+
+// Needed for unpacking handles to Go values.
+uintptr_t R_handleOf(SEXP p, const char *cls) {
+	if (TYPEOF(p) != EXTPTRSXP || R_ExternalPtrTag(p) != install(cls)) {
+		return 0;
+	}
+	return (uintptr_t)R_ExternalPtrAddr(p);
+}{{end}}{{if .NeedCallbacks}}
+
+// Needed for calling R functions from Go.
+SEXP R_callFunction(SEXP fn, SEXP args, int *failed) {
+	int n = length(args);
+	SEXP call = PROTECT(allocList(n + 1));
+	SET_TYPEOF(call, LANGSXP);
+	SETCAR(call, fn);
+	SEXP arg = CDR(call);
+	for (int i = 0; i < n; i++) {
+		SETCAR(arg, VECTOR_ELT(args, i));
+		arg = CDR(arg);
+	}
+	SEXP r = R_tryEval(call, R_GlobalEnv, failed);
+	UNPROTECT(1);
+	return r;
+}{{end}}{{if .NeedClasses}}
+
+// Needed for giving R values the S3 class of their Go type.
+void R_addClass(SEXP p, const char *cls) {
+	SEXP old = getAttrib(p, R_ClassSymbol);
+	int n = length(old);
+	SEXP class = PROTECT(allocVector(STRSXP, n + 1));
+	SET_STRING_ELT(class, 0, mkCharCE(cls, CE_UTF8));
+	for (int i = 0; i < n; i++) {
+		SET_STRING_ELT(class, i + 1, STRING_ELT(old, i));
+	}
+	setAttrib(p, R_ClassSymbol, class);
+	UNPROTECT(1);
+}{{end}}{{if .NeedArrow}}
+
+// Needed for releasing Arrow C data interface arrays.
+static void R_finalizeArrowArray(SEXP p) {
+	struct ArrowArray *a = (struct ArrowArray*)R_ExternalPtrAddr(p);
+	if (a != NULL) {
+		if (a->release != NULL) {
+			a->release(a);
+		}
+		free(a);
+	}
+	R_ClearExternalPtr(p);
+}
+
+// Needed for releasing Arrow C data interface schemas.
+static void R_finalizeArrowSchema(SEXP p) {
+	struct ArrowSchema *s = (struct ArrowSchema*)R_ExternalPtrAddr(p);
+	if (s != NULL) {
+		if (s->release != NULL) {
+			s->release(s);
+		}
+		free(s);
+	}
+	R_ClearExternalPtr(p);
+}
+
+// Needed for packing Arrow C data interface arrays.
+SEXP R_makeArrowArray(struct ArrowArray *a) {
+	SEXP p = PROTECT(R_MakeExternalPtr(a, install("ArrowArray"), R_NilValue));
+	R_RegisterCFinalizerEx(p, R_finalizeArrowArray, TRUE);
+	setAttrib(p, R_ClassSymbol, mkString("ArrowArray"));
+	UNPROTECT(1);
+	return p;
+}
+
+// Needed for packing Arrow C data interface schemas.
+SEXP R_makeArrowSchema(struct ArrowSchema *s) {
+	SEXP p = PROTECT(R_MakeExternalPtr(s, install("ArrowSchema"), R_NilValue));
+	R_RegisterCFinalizerEx(p, R_finalizeArrowSchema, TRUE);
+	setAttrib(p, R_ClassSymbol, mkString("ArrowSchema"));
+	UNPROTECT(1);
+	return p;
+}{{end}}{{if or .NeedDynamic .NeedBig .NeedArrow}}
+
+// Needed for unpacking R values by type.
+int R_typeof(SEXP p) {
+	return TYPEOF(p);
+}{{end}}{{range $func := .Funcs}}{{$params := $func.Params}}
+
+SEXP {{snake $func.Ident}}({{c $params}}) {
+	return Wrapped_{{$func.Ident}}({{names false $params}});
 }{{end}}
 `))
 }
@@ -6,6 +6,7 @@ package codegen
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/mod/semver"
 
@@ -40,6 +41,19 @@ LazyData: true
 	if err != nil {
 		return fmt.Errorf("failed to write DESCRIPTION file: %w", err)
 	}
+	var imports []string
+	if info.NeedInt64() && info.Options.Int64 == pkg.Integer64 {
+		imports = append(imports, "bit64")
+	}
+	if info.NeedBig() && info.Options.GMP {
+		imports = append(imports, "gmp")
+	}
+	if imports != nil {
+		_, err = fmt.Fprintf(w, "Imports: %s\n", strings.Join(imports, ", "))
+		if err != nil {
+			return fmt.Errorf("failed to write DESCRIPTION file: %w", err)
+		}
+	}
 	w.Close()
 
 	return nil
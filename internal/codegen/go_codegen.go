@@ -7,7 +7,6 @@ package codegen
 import (
 	"fmt"
 	"go/types"
-	"reflect"
 	"sort"
 	"strings"
 	"text/template"
@@ -22,10 +21,20 @@ func GoFuncTemplate() *template.Template {
 		"varsOf":     varsOf,
 		"go":         goParams,
 		"anon":       anonymous,
+		"call":       call,
+		"int64":      int64Helpers,
 		"types":      typeNames,
 		"mangle":     pkg.Mangle,
+		"unpack":     unpackParam,
 		"unpackSEXP": unpackSEXPFuncGo,
 		"packSEXP":   packSEXPFuncGo,
+		"time":       timeHelpers,
+		"factor":     factorHelpers,
+		"dynamic":    dynamicHelpers,
+		"big":        bigHelpers,
+		"array":      arrayHelpers,
+		"image":      imageHelpers,
+		"arrow":      arrowHelpers,
 		"dec":        func(i int) int { return i - 1 },
 	}).Parse(`{{$pkg := .Pkg}}// Code generated by rgnonomic/rgo; DO NOT EDIT.
 
@@ -36,25 +45,90 @@ package main
 #include <R.h>
 #include <Rinternals.h>
 extern void R_error(char *s);
+extern void R_warning(char *s);
 
 // TODO(kortschak): Only emit these when needed.
 extern Rboolean Rf_isNull(SEXP s);
 extern _GoString_ R_gostring(SEXP x, R_xlen_t i);
 extern int getListElementIndex(SEXP list, const char *str);
+{{if .NeedFrames}}
+extern void R_setDataFrame(SEXP p, int nrow);
+{{end -}}
+{{if .NeedCallbacks}}
+extern SEXP R_callFunction(SEXP fn, SEXP args, int *failed);
+{{end -}}
+{{if .NeedClasses}}
+extern void R_addClass(SEXP p, const char *cls);
+{{end -}}
+{{if or .NeedDynamic .NeedBig .NeedArrow}}
+extern int R_typeof(SEXP p);
+{{end -}}
+{{if .NeedArrow}}
+#include <stdint.h>
+#include <stdlib.h>
+
+#ifndef ARROW_C_DATA_INTERFACE
+#define ARROW_C_DATA_INTERFACE
+
+#define ARROW_FLAG_DICTIONARY_ORDERED 1
+#define ARROW_FLAG_NULLABLE 2
+#define ARROW_FLAG_MAP_KEYS_SORTED 4
+
+struct ArrowSchema {
+	const char* format;
+	const char* name;
+	const char* metadata;
+	int64_t flags;
+	int64_t n_children;
+	struct ArrowSchema** children;
+	struct ArrowSchema* dictionary;
+	void (*release)(struct ArrowSchema*);
+	void* private_data;
+};
+
+struct ArrowArray {
+	int64_t length;
+	int64_t null_count;
+	int64_t offset;
+	int64_t n_buffers;
+	int64_t n_children;
+	const void** buffers;
+	struct ArrowArray** children;
+	struct ArrowArray* dictionary;
+	void (*release)(struct ArrowArray*);
+	void* private_data;
+};
+
+#endif  // ARROW_C_DATA_INTERFACE
+
+extern SEXP R_makeArrowArray(struct ArrowArray *p);
+extern SEXP R_makeArrowSchema(struct ArrowSchema *p);
+{{end -}}
+{{if .NeedHandles}}
+#include <stdint.h>
+extern SEXP R_makeHandle(uintptr_t h, const char *cls);
+extern uintptr_t R_handleOf(SEXP p, const char *cls);
+{{end -}}
 */
 import "C"
 
 import (
 	"fmt"
-	"unsafe"
+{{if or .NeedTime .NeedImages}}	"math"
+{{end}}{{if .NeedDynamic}}	"reflect"
+	"sort"
+{{end}}{{if or (and .NeedInt64 (eq .Options.Int64 "character")) .NeedImages}}	"strconv"
+{{end}}{{if or .NeedCallbacks .NeedDynamic}}	"strings"
+{{end}}{{if .NeedHandles}}	"sync"
+{{end}}	"unsafe"
 
 {{with imports .}}{{range $p := .}}	"{{.}}"
 {{end}}
 {{end}}	"{{$pkg.Path}}"
 )
-{{$resultNeedsList := false}}{{range $func := .Funcs}}{{$params := varsOf $func.Signature.Params}}{{$results := varsOf $func.Signature.Results}}
-//export Wrapped_{{$func.Name}}
-func Wrapped_{{$func.Name}}({{go "_R_" $params}}) C.SEXP {
+{{$resultNeedsList := false}}{{range $func := .Funcs}}{{$params := $func.Params}}{{$results := varsOf $func.Signature.Results}}
+//export Wrapped_{{$func.Ident}}
+func Wrapped_{{$func.Ident}}({{go "_R_" $params}}) C.SEXP {
 	defer func() {
 		r := recover()
 		if r != nil {
@@ -64,12 +138,12 @@ func Wrapped_{{$func.Name}}({{go "_R_" $params}}) C.SEXP {
 		}
 	}()
 
-	{{range $i, $p := $params}}_p{{$i}} := unpackSEXP{{mangle $p.Type}}(_R_{{$p.Name}})
-	{{end}}{{with $results}}{{anon . "_r" false}} := {{end}}{{$pkg.Name}}.{{$func.Name}}({{anon $params "_p" false}}{{if $func.Signature.Variadic}}...{{end}})
-	{{with $results}}return packSEXP_{{$func.Name}}({{anon . "_r" false}}){{else}}return C.R_NilValue{{end}}
+	{{range $i, $p := $params}}{{unpack $.Options $func $i $p}}
+	{{end}}{{with $results}}{{anon . "_r" false}} := {{end}}{{call $pkg.Name $func}}
+	{{with $results}}return packSEXP_{{$func.Ident}}({{anon . "_r" false}}){{else}}return C.R_NilValue{{end}}
 }
 
-{{if $results}}func packSEXP_{{$func.Name}}({{anon $results "p" true}}) C.SEXP {
+{{if $results}}func packSEXP_{{$func.Ident}}({{anon $results "p" true}}) C.SEXP {
 {{$l := len $results -}}
 {{- if eq $l 1 -}}
 {{- $p := index $results 0}}	return packSEXP{{mangle $p.Type}}({{if $p.Name}}{{$p.Name}}{{else}}p0{{end -}})
@@ -86,8 +160,65 @@ func Wrapped_{{$func.Name}}({{go "_R_" $params}}) C.SEXP {
 }
 {{end}}{{end}}
 {{/* TODO(kortschak): Hoist C.SEXP unpacking for basic types out to the C code. */ -}}
-{{- .Unpackers.Types | unpackSEXP -}}
-{{- .Packers.Types | packSEXP}}func main() {}
+{{- .Unpackers.Types | unpackSEXP .Options -}}
+{{- .Packers.Types | packSEXP .Options}}{{if .NeedInt64}}{{int64 .Options}}{{end}}{{if .NeedTime}}{{time .}}{{end}}{{with .Enums}}{{factor .}}{{end}}{{if .NeedBig}}{{big .Options}}{{end}}{{if or .NeedArrays .NeedImages}}{{array}}{{end}}{{if .NeedImages}}{{image .Options}}{{end}}{{if .NeedArrow}}{{arrow}}{{end}}{{if .NeedDynamic}}{{dynamic .Options}}{{end}}{{if .NeedHandles}}// handles holds Go values referred to by R external pointers.
+var handles = struct {
+	sync.Mutex
+	next uintptr
+	vals map[uintptr]interface{}
+}{vals: make(map[uintptr]interface{})}
+
+// packHandle returns an R external pointer with the given class
+// referring to v.
+func packHandle(v interface{}, class string) C.SEXP {
+	handles.Lock()
+	handles.next++
+	h := handles.next
+	handles.vals[h] = v
+	handles.Unlock()
+	cls := C.CString(class)
+	defer C.free(unsafe.Pointer(cls))
+	return C.R_makeHandle(C.uintptr_t(h), cls)
+}
+
+// unpackHandle returns the Go value referred to by the R external
+// pointer p which must have the given class.
+func unpackHandle(p C.SEXP, class string) interface{} {
+	cls := C.CString(class)
+	defer C.free(unsafe.Pointer(cls))
+	h := uintptr(C.R_handleOf(p, cls))
+	if h == 0 {
+		panic(fmt.Sprintf("argument is not a %s handle", class))
+	}
+	handles.Lock()
+	v, ok := handles.vals[h]
+	handles.Unlock()
+	if !ok {
+		panic(fmt.Sprintf("released %s handle", class))
+	}
+	return v
+}
+
+// releaseHandle is called by the R finalizer of a handle.
+//export releaseHandle
+func releaseHandle(h C.uintptr_t) {
+	handles.Lock()
+	delete(handles.vals, uintptr(h))
+	handles.Unlock()
+}
+
+{{end}}{{if .NeedCallbacks}}// callR calls the R function fn with the elements of the list args as
+// its arguments. It returns an error if the call raises an R error.
+func callR(fn, args C.SEXP) (C.SEXP, error) {
+	var failed C.int
+	r := C.R_callFunction(fn, args, &failed)
+	if failed != 0 {
+		return nil, fmt.Errorf("R error: %s", strings.TrimSpace(C.GoString(C.R_curErrorBuf())))
+	}
+	return r, nil
+}
+
+{{end}}func main() {}
 `))
 }
 
@@ -98,6 +229,10 @@ func imports(info *pkg.Info) []string {
 	pkgs := make(map[string]bool)
 	for _, pack := range []map[string]types.Type{info.Unpackers, info.Packers} {
 		for _, p := range pack {
+			if info.Options.IsHandle(p) {
+				// Handle types are not walked into.
+				p = p.(*types.Pointer).Elem()
+			}
 			named, ok := p.(*types.Named)
 			if !ok {
 				continue
@@ -109,6 +244,37 @@ func imports(info *pkg.Info) []string {
 			pkgs[pkg.Path()] = true
 		}
 	}
+	// Struct pointers that fields are flattened through
+	// are named when they are allocated by unpackers.
+	for _, p := range info.Unpackers {
+		s, ok := p.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for _, f := range info.Options.Fields(s) {
+			for _, v := range f.Via {
+				typePkgs(pkgs, v.Var.Type(), us)
+			}
+		}
+	}
+	// Type arguments of instantiated generic functions
+	// are named in the wrapped calls.
+	for _, fn := range info.Funcs {
+		for _, typ := range fn.TypeArgs {
+			typePkgs(pkgs, typ, us)
+		}
+	}
+	if info.NeedTime() {
+		pkgs["time"] = true
+	}
+	if info.NeedBig() {
+		pkgs["math/big"] = true
+	}
+	if info.NeedImages() {
+		pkgs["image"] = true
+		pkgs["image/color"] = true
+		pkgs["image/draw"] = true
+	}
 	paths := make([]string, 0, len(pkgs))
 	for p := range pkgs {
 		paths = append(paths, p)
@@ -118,6 +284,105 @@ func imports(info *pkg.Info) []string {
 	return paths
 }
 
+// typePkgs adds the paths of packages other than us that are named in the
+// spelling of typ to pkgs.
+func typePkgs(pkgs map[string]bool, typ types.Type, us *types.Package) {
+	switch typ := typ.(type) {
+	case *types.Named:
+		if pkg := typ.Obj().Pkg(); pkg != nil && pkg != us {
+			pkgs[pkg.Path()] = true
+		}
+		args := typ.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			typePkgs(pkgs, args.At(i), us)
+		}
+	case *types.Pointer:
+		typePkgs(pkgs, typ.Elem(), us)
+	case *types.Slice:
+		typePkgs(pkgs, typ.Elem(), us)
+	case *types.Array:
+		typePkgs(pkgs, typ.Elem(), us)
+	case *types.Map:
+		typePkgs(pkgs, typ.Key(), us)
+		typePkgs(pkgs, typ.Elem(), us)
+	}
+}
+
+// unpackParam returns the Go statement unpacking the ith parameter, p, of
+// the wrapper function for fn. Variadic parameters are unpacked from the
+// list of R ... arguments element-wise. Pointer receivers are never nil.
+func unpackParam(opts pkg.Options, fn pkg.FuncInfo, i int, p *types.Var) string {
+	if i == 0 && fn.Receiver() != nil {
+		if ptr, ok := p.Type().(*types.Pointer); ok {
+			if opts.IsHandle(ptr) {
+				// NULL is not a handle.
+				return fmt.Sprintf("_p0 := unpackHandle(_R_%s, %q).(%s)", p.Name(), nameOf(ptr.Elem()), nameOf(ptr))
+			}
+			return fmt.Sprintf(`_p0 := unpackSEXP%[3]s(_R_%[1]s)
+	if _p0 == nil {
+		panic("nil receiver for %[2]s")
+	}`, p.Name(), fn.QualifiedName(), pkg.Mangle(ptr))
+		}
+	}
+	if p != fn.Variadic() {
+		return fmt.Sprintf("_p%d := unpackSEXP%s(_R_%s)", i, pkg.Mangle(p.Type()), p.Name())
+	}
+	elem := p.Type().(*types.Slice).Elem()
+	return fmt.Sprintf(`_p%[1]d := make(%[2]s, C.Rf_xlength(_R_%[3]s))
+	for i := range _p%[1]d {
+		_p%[1]d[i] = unpackSEXP%[4]s(C.VECTOR_ELT(_R_%[3]s, C.R_xlen_t(i)))
+	}`, i, nameOf(p.Type()), p.Name(), pkg.Mangle(elem))
+}
+
+// call returns the Go call expression for fn using the numbered parameters
+// of the wrapper function. Methods are called on the first parameter and
+// instantiated generic functions are called with explicit type arguments.
+// Accessors read or assign the accessed constant or variable.
+func call(pkgName string, fn pkg.FuncInfo) string {
+	params := fn.Params()
+	if obj := fn.Object; obj != nil {
+		if len(params) == 0 {
+			// Constants may be read as a type other
+			// than their default type.
+			typ := fn.Func.Type().(*types.Signature).Results().At(0).Type()
+			if !types.Identical(typ, types.Default(obj.Type())) {
+				return fmt.Sprintf("%s(%s.%s)", nameOf(typ), pkgName, obj.Name())
+			}
+			return fmt.Sprintf("%s.%s", pkgName, obj.Name())
+		}
+		return fmt.Sprintf("%s.%s = _p0", pkgName, obj.Name())
+	}
+	recv := pkgName
+	if fn.Receiver() != nil {
+		recv = "_p0"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s.%s", recv, fn.Func.Name())
+	if len(fn.TypeArgs) != 0 {
+		args := make([]string, len(fn.TypeArgs))
+		for i, typ := range fn.TypeArgs {
+			args[i] = nameOf(typ)
+		}
+		fmt.Fprintf(&buf, "[%s]", strings.Join(args, ", "))
+	}
+	buf.WriteString("(")
+	first := 0
+	if fn.Receiver() != nil {
+		first = 1
+	}
+	for i := first; i < len(params); i++ {
+		if i != first {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "_p%d", i)
+	}
+	if fn.Signature().Variadic() {
+		buf.WriteString("...")
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
 // goParams returns a comma-separated list of C.SEXP parameters using the
 // parameter names in vars with the mangling prefix applied.
 func goParams(prefix string, vars []*types.Var) string {
@@ -190,13 +455,3 @@ func nameOf(typ types.Type) string {
 		return pkg.Name()
 	})
 }
-
-// targetFieldName returns the rgo struct tag of the ith field of s if
-// it exists, otherwise the name of the field.
-func targetFieldName(s *types.Struct, i int) string {
-	tag := reflect.StructTag(s.Tag(i)).Get("rgo")
-	if tag != "" {
-		return tag
-	}
-	return s.Field(i).Name()
-}
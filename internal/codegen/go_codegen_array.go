@@ -0,0 +1,120 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// arrayLoops returns the opening of the loops over each element of the
+// nested array held by v, the element selector, the column-major index
+// of the element in a flat slice, and the closing of the loops.
+func arrayLoops(v string, dims []int64) (open, elem, index, close string) {
+	var buf strings.Builder
+	elem = v
+	for i := range dims {
+		fmt.Fprintf(&buf, "%s\tfor i%d := range %s {\n", strings.Repeat("\t", i), i, elem)
+		elem += fmt.Sprintf("[i%d]", i)
+	}
+	open = buf.String()
+
+	index = fmt.Sprintf("i%d", len(dims)-1)
+	for i := len(dims) - 2; i >= 0; i-- {
+		if i == len(dims)-2 {
+			index = fmt.Sprintf("i%d+%d*%s", i, dims[i], index)
+		} else {
+			index = fmt.Sprintf("i%d+%d*(%s)", i, dims[i], index)
+		}
+	}
+
+	buf.Reset()
+	for i := len(dims) - 1; i >= 0; i-- {
+		fmt.Fprintf(&buf, "%s\t}\n", strings.Repeat("\t", i))
+	}
+	close = buf.String()
+
+	return open, elem, index, close
+}
+
+// dimList returns the comma-separated lengths of dims.
+func dimList(dims []int64) string {
+	s := make([]string, len(dims))
+	for i, d := range dims {
+		s[i] = fmt.Sprint(d)
+	}
+	return strings.Join(s, ", ")
+}
+
+func packNestedArray(buf *bytes.Buffer, dims []int64, elem *types.Basic) {
+	n := int64(1)
+	for _, d := range dims {
+		n *= d
+	}
+	slice := types.NewSlice(elem)
+	open, sel, index, close := arrayLoops("p", dims)
+	indent := strings.Repeat("\t", len(dims)+1)
+	fmt.Fprintf(buf, "\ts := make(%s, %d)\n%s%ss[%s] = %s\n%s", nameOf(slice), n, open, indent, index, sel, close)
+	fmt.Fprintf(buf, `	r := packSEXP%s(s)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	setDim(r, %s)
+	return r
+`, pkg.Mangle(slice), dimList(dims))
+}
+
+func unpackNestedArray(buf *bytes.Buffer, typ types.Type, dims []int64, elem *types.Basic) {
+	slice := types.NewSlice(elem)
+	open, sel, index, close := arrayLoops("r", dims)
+	indent := strings.Repeat("\t", len(dims)+1)
+	fmt.Fprintf(buf, `	checkDim(p, "%[1]s", %[2]s)
+	s := unpackSEXP%[3]s(p)
+	var r %[1]s
+`, nameOf(typ), dimList(dims), pkg.Mangle(slice))
+	fmt.Fprintf(buf, "%s%s%s = s[%s]\n%s\treturn r\n", open, indent, sel, index, close)
+}
+
+// arrayHelpers returns the Go source for the functions setting and checking
+// the dim attribute of R arrays.
+func arrayHelpers() string {
+	// Maximum length array type for this element type.
+	type a [1 << 47]int32
+	var buf strings.Builder
+	err := arrayHelpersTmpl.Execute(&buf, struct{ Max int }{Max: len(&a{})})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+var arrayHelpersTmpl = template.Must(template.New("array").Parse(`// setDim sets the dim attribute of the R vector p to dim.
+func setDim(p C.SEXP, dim ...int32) {
+	d := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(len(dim)))
+	C.Rf_protect(d)
+	defer C.Rf_unprotect(1)
+	copy((*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(d)))[:len(dim)], dim)
+	C.Rf_setAttrib(p, C.R_DimSymbol, d)
+}
+
+// checkDim panics if the dim attribute of the R array p does not
+// match the dimensions, dim, of the Go array type typ.
+func checkDim(p C.SEXP, typ string, dim ...int32) {
+	d := C.Rf_getAttrib(p, C.R_DimSymbol)
+	if C.Rf_isInteger(d) == 0 || int(C.Rf_xlength(d)) != len(dim) {
+		panic(fmt.Sprintf("argument is not a %d dimensional array for Go %s value", len(dim), typ))
+	}
+	for i, n := range (*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(d)))[:len(dim)] {
+		if n != dim[i] {
+			panic(fmt.Sprintf("dimension %d of array has length %d, not %d, for Go %s value", i+1, n, dim[i], typ))
+		}
+	}
+}
+
+`))
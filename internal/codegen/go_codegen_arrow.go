@@ -0,0 +1,83 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// arrowName returns the C struct name of the Arrow C data interface
+// struct type.
+func arrowName(kind pkg.ArrowKind) string {
+	switch kind {
+	case pkg.ArrowArray:
+		return "ArrowArray"
+	case pkg.ArrowSchema:
+		return "ArrowSchema"
+	default:
+		panic(fmt.Sprintf("unhandled Arrow struct type: %d", kind))
+	}
+}
+
+func packArrow(buf *bytes.Buffer, kind pkg.ArrowKind) {
+	fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return pack%s(unsafe.Pointer(p))
+`, arrowName(kind))
+}
+
+func unpackArrow(buf *bytes.Buffer, typ types.Type) {
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	return (%[1]s)(arrowOf(p, "%[1]s"))
+`, nameOf(typ))
+}
+
+// arrowHelpers returns the Go source for the functions converting between
+// Arrow C data interface structs and R external pointers.
+func arrowHelpers() string {
+	return `// packArrowArray returns an R external pointer to an ArrowArray
+// holding the array pointed to by p. The array is moved to C memory
+// owned by R and p is marked released.
+func packArrowArray(p unsafe.Pointer) C.SEXP {
+	src := (*C.struct_ArrowArray)(p)
+	a := (*C.struct_ArrowArray)(C.malloc(C.sizeof_struct_ArrowArray))
+	*a = *src
+	src.release = nil
+	return C.R_makeArrowArray(a)
+}
+
+// packArrowSchema returns an R external pointer to an ArrowSchema
+// holding the schema pointed to by p. The schema is moved to C memory
+// owned by R and p is marked released.
+func packArrowSchema(p unsafe.Pointer) C.SEXP {
+	src := (*C.struct_ArrowSchema)(p)
+	s := (*C.struct_ArrowSchema)(C.malloc(C.sizeof_struct_ArrowSchema))
+	*s = *src
+	src.release = nil
+	return C.R_makeArrowSchema(s)
+}
+
+// arrowOf returns the address of the Arrow C data interface struct
+// held by the R external pointer p. The struct remains owned by R.
+func arrowOf(p C.SEXP, typ string) unsafe.Pointer {
+	if C.R_typeof(p) != C.EXTPTRSXP {
+		panic(fmt.Sprintf("argument is not an external pointer for Go %s value", typ))
+	}
+	addr := C.R_ExternalPtrAddr(p)
+	if addr == nil {
+		panic(fmt.Sprintf("external pointer is NULL for Go %s value", typ))
+	}
+	return addr
+}
+
+`
+}
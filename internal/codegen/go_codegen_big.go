@@ -0,0 +1,434 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// bigSuffix returns the name suffix of the math/big helper functions for
+// the given number type.
+func bigSuffix(kind pkg.BigKind) string {
+	switch kind {
+	case pkg.BigInt:
+		return "BigInts"
+	case pkg.BigRat:
+		return "BigRats"
+	case pkg.BigFloat:
+		return "BigFloats"
+	default:
+		panic(fmt.Sprintf("unhandled math/big type: %d", kind))
+	}
+}
+
+// bigName returns the Go name of the math/big number type.
+func bigName(kind pkg.BigKind) string {
+	switch kind {
+	case pkg.BigInt:
+		return "big.Int"
+	case pkg.BigRat:
+		return "big.Rat"
+	case pkg.BigFloat:
+		return "big.Float"
+	default:
+		panic(fmt.Sprintf("unhandled math/big type: %d", kind))
+	}
+}
+
+func packBig(buf *bytes.Buffer, kind pkg.BigKind, ptr, slice bool) {
+	suffix := bigSuffix(kind)
+	name := bigName(kind)
+	switch {
+	case !ptr && !slice:
+		fmt.Fprintf(buf, "\treturn pack%s([]*%s{&p})\n", suffix, name)
+	case ptr && !slice:
+		fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return pack%s([]*%s{p})
+`, suffix, name)
+	case ptr && slice:
+		fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return pack%s(p)
+`, suffix)
+	default:
+		fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	s := make([]*%s, len(p))
+	for i := range p {
+		s[i] = &p[i]
+	}
+	return pack%s(s)
+`, name, suffix)
+	}
+}
+
+func unpackBig(buf *bytes.Buffer, kind pkg.BigKind, ptr, slice bool) {
+	suffix := bigSuffix(kind)
+	name := bigName(kind)
+	if ptr || slice {
+		fmt.Fprintln(buf, "\tif C.Rf_isNull(p) != 0 {\n\t\treturn nil\n\t}")
+	}
+	switch {
+	case !ptr && !slice:
+		fmt.Fprintf(buf, `	r := unpack%s(p)[0]
+	if r == nil {
+		panic("NA not allowed for Go %s value")
+	}
+	return *r
+`, suffix, name)
+	case ptr && !slice:
+		fmt.Fprintf(buf, "\treturn unpack%s(p)[0]\n", suffix)
+	case ptr && slice:
+		fmt.Fprintf(buf, "\treturn unpack%s(p)\n", suffix)
+	default:
+		fmt.Fprintf(buf, `	s := unpack%s(p)
+	r := make([]%s, len(s))
+	for i, v := range s {
+		if v == nil {
+			panic(fmt.Sprintf("NA not allowed for Go %s value at index %%d", i+1))
+		}
+		r[i] = *v
+	}
+	return r
+`, suffix, name, name)
+	}
+}
+
+// bigHelpers returns the Go source for the functions converting between
+// slices of math/big numbers and R vectors in the representation given
+// by opts.
+func bigHelpers(opts pkg.Options) string {
+	// Maximum length array type for this element type.
+	type a [1 << 47]int32
+	var buf strings.Builder
+	err := bigHelpersTmpl.Execute(&buf, struct {
+		Max int
+		GMP bool
+	}{Max: len(&a{}), GMP: opts.GMP})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+var bigHelpersTmpl = template.Must(template.New("big").Parse(`// packStringsNA returns an R character vector holding the n strings
+// returned by str. Strings reported as not ok are packed as NA.
+func packStringsNA(n int, str func(i int) (s string, ok bool)) C.SEXP {
+	r := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(n))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	for i := 0; i < n; i++ {
+		s, ok := str(i)
+		if !ok {
+			C.SET_STRING_ELT(r, C.R_xlen_t(i), C.R_NaString)
+			continue
+		}
+		C.SET_STRING_ELT(r, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(s), C.int(len(s)), C.CE_UTF8))
+	}
+	return r
+}
+
+// unpackStringsNA calls fn with the index and value of each element of
+// the R character vector p that is not NA.
+func unpackStringsNA(p C.SEXP, fn func(i int, s string)) {
+	for i := 0; i < int(C.Rf_xlength(p)); i++ {
+		if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+			continue
+		}
+		fn(i, C.R_gostring(p, C.R_xlen_t(i)))
+	}
+}
+
+// isGMP returns whether p is a gmp vector of the given class.
+func isGMP(p C.SEXP, class string) bool {
+	cls := C.CString(class)
+	defer C.free(unsafe.Pointer(cls))
+	return C.Rf_isNull(p) == 0 && C.R_typeof(p) == C.RAWSXP && C.Rf_inherits(p, cls) != 0
+}
+
+// gmpWords returns the R raw vector p as native-endian 32-bit words.
+func gmpWords(p C.SEXP) []int32 {
+	n := int(C.Rf_xlength(p)) / 4
+	return (*[{{.Max}}]int32)(unsafe.Pointer(C.RAW(p)))[:n:n]
+}
+
+// decodeBigz returns the values in the gmp bigz serialization w. Each
+// value is held as its number of words, or -1 for NA, its sign and the
+// 32-bit words of its absolute value, most significant first. NA values
+// are returned as nil.
+func decodeBigz(w []int32) []*big.Int {
+	if len(w) == 0 {
+		return nil
+	}
+	r := make([]*big.Int, w[0])
+	w = w[1:]
+	for i := range r {
+		if len(w) == 0 {
+			panic("invalid gmp bigz value")
+		}
+		size := int(w[0])
+		if size <= 0 {
+			w = w[1:]
+			continue
+		}
+		if len(w) < size+2 {
+			panic("invalid gmp bigz value")
+		}
+		b := make([]byte, 4*size)
+		for j, v := range w[2 : size+2] {
+			b[4*j] = byte(uint32(v) >> 24)
+			b[4*j+1] = byte(uint32(v) >> 16)
+			b[4*j+2] = byte(uint32(v) >> 8)
+			b[4*j+3] = byte(v)
+		}
+		r[i] = new(big.Int).SetBytes(b)
+		if w[1] < 0 {
+			r[i].Neg(r[i])
+		}
+		w = w[size+2:]
+	}
+	return r
+}
+
+// unpackBigz returns the values in the gmp bigz vector p.
+func unpackBigz(p C.SEXP) []*big.Int {
+	return decodeBigz(gmpWords(p))
+}
+
+// unpackBigzAttrib returns the values in the named gmp bigz attribute
+// of p.
+func unpackBigzAttrib(p C.SEXP, name string) []*big.Int {
+	sym := C.CString(name)
+	defer C.free(unsafe.Pointer(sym))
+	a := C.Rf_getAttrib(p, C.Rf_install(sym))
+	if C.Rf_isNull(a) != 0 {
+		panic(fmt.Sprintf("missing gmp %s attribute", name))
+	}
+	return unpackBigz(a)
+}
+{{- if .GMP}}
+
+// encodeBigz returns the gmp bigz serialization of the values in p as
+// an R raw vector. Nil values are encoded as NA.
+func encodeBigz(p []*big.Int) C.SEXP {
+	w := []int32{int32(len(p))}
+	for _, v := range p {
+		if v == nil {
+			w = append(w, -1)
+			continue
+		}
+		b := v.Bytes()
+		if len(b) == 0 {
+			b = []byte{0}
+		}
+		if pad := len(b) % 4; pad != 0 {
+			b = append(make([]byte, 4-pad), b...)
+		}
+		w = append(w, int32(len(b)/4), int32(v.Sign()))
+		for j := 0; j < len(b); j += 4 {
+			w = append(w, int32(uint32(b[j])<<24|uint32(b[j+1])<<16|uint32(b[j+2])<<8|uint32(b[j+3])))
+		}
+	}
+	r := C.Rf_allocVector(C.RAWSXP, C.R_xlen_t(4*len(w)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	copy(gmpWords(r), w)
+	return r
+}
+
+// setGMPClass sets the class attribute of p to the given gmp class.
+func setGMPClass(p C.SEXP, class string) {
+	cls := C.CString(class)
+	defer C.free(unsafe.Pointer(cls))
+	c := C.Rf_mkString(cls)
+	C.Rf_protect(c)
+	defer C.Rf_unprotect(1)
+	C.Rf_classgets(p, c)
+}
+{{- end}}
+
+// packBigInts returns an R {{if .GMP}}gmp bigz{{else}}character{{end}} vector holding the values in p.
+// Nil values are packed as NA.
+func packBigInts(p []*big.Int) C.SEXP {
+{{- if .GMP}}
+	r := encodeBigz(p)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	setGMPClass(r, "bigz")
+	return r
+{{- else}}
+	return packStringsNA(len(p), func(i int) (string, bool) {
+		if p[i] == nil {
+			return "", false
+		}
+		return p[i].String(), true
+	})
+{{- end}}
+}
+
+// unpackBigInts returns the values in the R character or gmp bigz
+// vector p. NA values are returned as nil.
+func unpackBigInts(p C.SEXP) []*big.Int {
+	if isGMP(p, "bigz") {
+		return unpackBigz(p)
+	}
+	r := make([]*big.Int, C.Rf_xlength(p))
+	unpackStringsNA(p, func(i int, s string) {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			panic(fmt.Sprintf("invalid Go big.Int value %q at index %d", s, i+1))
+		}
+		r[i] = v
+	})
+	return r
+}
+
+// packBigRats returns an R {{if .GMP}}gmp bigq{{else}}character{{end}} vector holding the values in p.
+// Nil values are packed as NA.
+func packBigRats(p []*big.Rat) C.SEXP {
+{{- if .GMP}}
+	num := make([]*big.Int, len(p))
+	den := make([]*big.Int, len(p))
+	for i, v := range p {
+		if v == nil {
+			continue
+		}
+		num[i] = v.Num()
+		den[i] = v.Denom()
+	}
+	r := encodeBigz(num)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	d := encodeBigz(den)
+	C.Rf_protect(d)
+	defer C.Rf_unprotect(1)
+	sym := C.CString("denominator")
+	defer C.free(unsafe.Pointer(sym))
+	C.Rf_setAttrib(r, C.Rf_install(sym), d)
+	setGMPClass(r, "bigq")
+	return r
+{{- else}}
+	return packStringsNA(len(p), func(i int) (string, bool) {
+		if p[i] == nil {
+			return "", false
+		}
+		return p[i].RatString(), true
+	})
+{{- end}}
+}
+
+// unpackBigRats returns the values in the R character, gmp bigq or gmp
+// bigz vector p. NA values are returned as nil.
+func unpackBigRats(p C.SEXP) []*big.Rat {
+	switch {
+	case isGMP(p, "bigq"):
+		num := unpackBigz(p)
+		den := unpackBigzAttrib(p, "denominator")
+		if len(den) != len(num) {
+			panic("invalid gmp bigq value")
+		}
+		r := make([]*big.Rat, len(num))
+		for i, n := range num {
+			if n == nil || den[i] == nil {
+				continue
+			}
+			if den[i].Sign() == 0 {
+				panic(fmt.Sprintf("zero denominator for Go big.Rat value at index %d", i+1))
+			}
+			r[i] = new(big.Rat).SetFrac(n, den[i])
+		}
+		return r
+	case isGMP(p, "bigz"):
+		num := unpackBigz(p)
+		r := make([]*big.Rat, len(num))
+		for i, n := range num {
+			if n != nil {
+				r[i] = new(big.Rat).SetInt(n)
+			}
+		}
+		return r
+	}
+	r := make([]*big.Rat, C.Rf_xlength(p))
+	unpackStringsNA(p, func(i int, s string) {
+		v, ok := new(big.Rat).SetString(s)
+		if !ok {
+			panic(fmt.Sprintf("invalid Go big.Rat value %q at index %d", s, i+1))
+		}
+		r[i] = v
+	})
+	return r
+}
+
+// packBigFloats returns an R character vector holding the values in p
+// formatted with the fewest digits that recover the value at its
+// precision. The precision of each value is held in the integer prec
+// attribute of the vector. Nil values are packed as NA.
+func packBigFloats(p []*big.Float) C.SEXP {
+	r := packStringsNA(len(p), func(i int) (string, bool) {
+		if p[i] == nil {
+			return "", false
+		}
+		return p[i].Text('g', -1), true
+	})
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	prec := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(prec)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(prec)))[:len(p):len(p)]
+	for i, v := range p {
+		if v == nil {
+			s[i] = int32(C.R_NaInt)
+			continue
+		}
+		s[i] = int32(v.Prec())
+	}
+	sym := C.CString("prec")
+	defer C.free(unsafe.Pointer(sym))
+	C.Rf_setAttrib(r, C.Rf_install(sym), prec)
+	return r
+}
+
+// unpackBigFloats returns the values in the R character vector p. The
+// precision of each value is taken from the integer prec attribute of
+// p if it is present, and is otherwise sufficient to hold the decimal
+// value with at least 64 bits. NA values are returned as nil.
+func unpackBigFloats(p C.SEXP) []*big.Float {
+	n := int(C.Rf_xlength(p))
+	sym := C.CString("prec")
+	defer C.free(unsafe.Pointer(sym))
+	var prec []int32
+	if a := C.Rf_getAttrib(p, C.Rf_install(sym)); C.Rf_isInteger(a) != 0 && int(C.Rf_xlength(a)) == n {
+		prec = (*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(a)))[:n:n]
+	}
+	r := make([]*big.Float, n)
+	unpackStringsNA(p, func(i int, s string) {
+		// Each decimal digit needs fewer than four bits.
+		bits := uint(4 * len(s))
+		if bits < 64 {
+			bits = 64
+		}
+		if prec != nil && C.int(prec[i]) != C.R_NaInt {
+			bits = uint(prec[i])
+		}
+		v, _, err := big.ParseFloat(s, 10, bits, big.ToNearestEven)
+		if err != nil {
+			panic(fmt.Sprintf("invalid Go big.Float value %q at index %d: %v", s, i+1, err))
+		}
+		r[i] = v
+	})
+	return r
+}
+
+`))
@@ -0,0 +1,430 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+func packDynamic(buf *bytes.Buffer) {
+	fmt.Fprintln(buf, "\treturn packDynamic(p)")
+}
+
+func unpackDynamic(buf *bytes.Buffer, typ types.Type) {
+	if _, ok := typ.(*types.Named); ok {
+		fmt.Fprintf(buf, "\treturn %s(unpackDynamic(p))\n", nameOf(typ))
+		return
+	}
+	fmt.Fprintln(buf, "\treturn unpackDynamic(p)")
+}
+
+// dynamicHelpers returns the Go source for the functions converting
+// between Go dynamically typed values and R values.
+func dynamicHelpers(opts pkg.Options) string {
+	// Maximum length array types for these element types.
+	type (
+		a [1 << 47]int32
+		b [1 << 46]float64
+		c [1 << 45]complex128
+		d [1 << 49]byte
+	)
+	var buf strings.Builder
+	err := dynamicHelpersTmpl.Execute(&buf, struct {
+		MaxInt     int
+		MaxReal    int
+		MaxComplex int
+		MaxRaw     int
+		Int64      bool
+		Integer64  bool
+	}{
+		MaxInt:     len(&a{}),
+		MaxReal:    len(&b{}),
+		MaxComplex: len(&c{}),
+		MaxRaw:     len(&d{}),
+		Int64:      opts.Int64 != "",
+		Integer64:  opts.Int64 == pkg.Integer64,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+var dynamicHelpersTmpl = template.Must(template.New("dynamic").Parse(`// packDynamic returns an R value holding the Go value v. Booleans,
+// numbers and strings are packed as atomic vectors of length one, and
+// slices and arrays of them as atomic vectors. Maps with string keys and
+// structs are packed as named lists, and other slices and arrays as
+// unnamed lists. Nil values are packed as NULL.
+func packDynamic(v interface{}) C.SEXP {
+	if v == nil {
+		return C.R_NilValue
+	}
+	return packValue(reflect.ValueOf(v))
+}
+
+// packValue returns an R value holding the Go value v.
+func packValue(v reflect.Value) C.SEXP {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return C.R_NilValue
+		}
+		return packValue(v.Elem())
+
+	case reflect.Int8, reflect.Uint8:
+		// Scalar bytes are integers, but slices of bytes are raw.
+		return packVector(reflect.ValueOf([]int32{int32(v.Convert(reflect.TypeOf(int32(0))).Int())}))
+
+	case reflect.Bool, reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.String:
+		s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		s.Index(0).Set(v)
+		return packVector(s)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return C.R_NilValue
+		}
+		if isAtomic(v.Type().Elem()) {
+			return packVector(v)
+		}
+		r := C.Rf_allocVector(C.VECSXP, C.R_xlen_t(v.Len()))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		for i := 0; i < v.Len(); i++ {
+			C.SET_VECTOR_ELT(r, C.R_xlen_t(i), packValue(v.Index(i)))
+		}
+		return r
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			panic(fmt.Sprintf("unhandled Go dynamic value type: %s", v.Type()))
+		}
+		if v.IsNil() {
+			return C.R_NilValue
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		names := make([]string, len(keys))
+		vals := make([]reflect.Value, len(keys))
+		for i, k := range keys {
+			names[i] = k.String()
+			vals[i] = v.MapIndex(k)
+		}
+		return packNamedList(names, vals)
+
+	case reflect.Struct:
+		t := v.Type()
+		var (
+			names []string
+			vals  []reflect.Value
+		)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup("rgo"); ok {
+				tag = strings.Split(tag, ",")[0]
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			names = append(names, name)
+			vals = append(vals, v.Field(i))
+		}
+		return packNamedList(names, vals)
+
+	default:
+		panic(fmt.Sprintf("unhandled Go dynamic value type: %s", v.Type()))
+	}
+}
+
+// isAtomic returns whether values of the Go type t are packed as the
+// elements of an R atomic vector.
+func isAtomic(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+// packVector returns an R atomic vector holding the elements of the
+// slice or array v. The elements of v must be atomic.
+func packVector(v reflect.Value) C.SEXP {
+	n := v.Len()
+	switch v.Type().Elem().Kind() {
+	case reflect.Bool:
+		r := C.Rf_allocVector(C.LGLSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.LOGICAL(r)))[:n:n]
+		for i := range s {
+			s[i] = 0
+			if v.Index(i).Bool() {
+				s[i] = 1
+			}
+		}
+		return r
+
+	case reflect.Int, reflect.Int16, reflect.Int32:
+		r := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(r)))[:n:n]
+		for i := range s {
+			x := v.Index(i).Int()
+			if x < -1<<31 || 1<<31-1 < x {
+				panic(fmt.Sprintf("value %d out of range of R integer for Go %s value at index %d", x, v.Type().Elem(), i+1))
+			}
+			s[i] = int32(x)
+		}
+		return r
+
+	case reflect.Uint, reflect.Uint16, reflect.Uint32:
+		r := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(r)))[:n:n]
+		for i := range s {
+			x := v.Index(i).Uint()
+			if v.Type().Elem().Kind() == reflect.Uint && 1<<31-1 < x {
+				panic(fmt.Sprintf("value %d out of range of R integer for Go %s value at index %d", x, v.Type().Elem(), i+1))
+			}
+			s[i] = int32(x)
+		}
+		return r
+{{if .Int64}}
+	case reflect.Int64:
+		s := make([]int64, n)
+		for i := range s {
+			s[i] = v.Index(i).Int()
+		}
+		return packInt64(s)
+
+	case reflect.Uint64:
+		s := make([]uint64, n)
+		for i := range s {
+			s[i] = v.Index(i).Uint()
+		}
+		return packUint64(s)
+{{else}}
+	case reflect.Int64, reflect.Uint64:
+		// There is no R representation of 64-bit
+		// integers without the Int64 option.
+		panic(fmt.Sprintf("unhandled Go dynamic value type %s: Int64 option not set", v.Type().Elem()))
+{{end}}
+	case reflect.Int8, reflect.Uint8:
+		r := C.Rf_allocVector(C.RAWSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxRaw}}]uint8)(unsafe.Pointer(C.RAW(r)))[:n:n]
+		for i := range s {
+			e := v.Index(i)
+			if e.Kind() == reflect.Int8 {
+				s[i] = uint8(e.Int())
+			} else {
+				s[i] = uint8(e.Uint())
+			}
+		}
+		return r
+
+	case reflect.Float32, reflect.Float64:
+		r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxReal}}]float64)(unsafe.Pointer(C.REAL(r)))[:n:n]
+		for i := range s {
+			s[i] = v.Index(i).Float()
+		}
+		return r
+
+	case reflect.Complex64, reflect.Complex128:
+		r := C.Rf_allocVector(C.CPLXSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		s := (*[{{.MaxComplex}}]complex128)(unsafe.Pointer(C.COMPLEX(r)))[:n:n]
+		for i := range s {
+			s[i] = v.Index(i).Complex()
+		}
+		return r
+
+	case reflect.String:
+		r := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(n))
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		for i := 0; i < n; i++ {
+			s := v.Index(i).String()
+			C.SET_STRING_ELT(r, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(s), C.int(len(s)), C.CE_UTF8))
+		}
+		return r
+
+	default:
+		panic(fmt.Sprintf("unhandled Go dynamic value type: %s", v.Type()))
+	}
+}
+
+// packNamedList returns an R list holding the Go values vals with the
+// given names.
+func packNamedList(names []string, vals []reflect.Value) C.SEXP {
+	r := C.Rf_allocVector(C.VECSXP, C.R_xlen_t(len(vals)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	n := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(len(names)))
+	C.Rf_protect(n)
+	defer C.Rf_unprotect(1)
+	for i, v := range vals {
+		C.SET_STRING_ELT(n, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(names[i]), C.int(len(names[i])), C.CE_UTF8))
+		C.SET_VECTOR_ELT(r, C.R_xlen_t(i), packValue(v))
+	}
+	C.setAttrib(r, C.R_NamesSymbol, n)
+	return r
+}
+
+// unpackDynamic returns a Go value holding the R value p. Atomic vectors
+// of length one are unpacked as bool, int, float64, complex128 or string
+// values, and other atomic vectors as slices of these types. Raw vectors
+// are unpacked as []byte and factors as strings. Lists with names for
+// all their elements are unpacked as map[string]interface{} and other
+// lists as []interface{}. NULL is unpacked as nil.
+func unpackDynamic(p C.SEXP) interface{} {
+	n := int(C.Rf_xlength(p))
+	var v interface{}
+	switch C.R_typeof(p) {
+	case C.NILSXP:
+		return nil
+
+	case C.LGLSXP:
+		r := make([]bool, n)
+		for i, b := range (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.LOGICAL(p)))[:n:n] {
+			if C.int(b) == C.R_NaInt {
+				panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+			}
+			r[i] = b != 0
+		}
+		v = r
+
+	case C.INTSXP:
+		if C.Rf_isFactor(p) != 0 {
+			levels := C.Rf_getAttrib(p, C.R_LevelsSymbol)
+			r := make([]string, n)
+			for i, c := range (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(p)))[:n:n] {
+				if C.int(c) == C.R_NaInt {
+					panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+				}
+				r[i] = C.R_gostring(levels, C.R_xlen_t(c-1))
+			}
+			v = r
+			break
+		}
+		r := make([]int, n)
+		for i, e := range (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(p)))[:n:n] {
+			if C.int(e) == C.R_NaInt {
+				panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+			}
+			r[i] = int(e)
+		}
+		v = r
+
+	case C.REALSXP:
+{{- if .Integer64}}
+		cls := C.CString("integer64")
+		is64 := C.Rf_inherits(p, cls) != 0
+		C.free(unsafe.Pointer(cls))
+		if is64 {
+			v = unpackInt64(p)
+			break
+		}
+{{- end}}
+		r := make([]float64, n)
+		for i, f := range (*[{{.MaxReal}}]float64)(unsafe.Pointer(C.REAL(p)))[:n:n] {
+			if C.R_IsNA(C.double(f)) != 0 {
+				panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+			}
+			r[i] = f
+		}
+		v = r
+
+	case C.CPLXSXP:
+		r := make([]complex128, n)
+		for i, c := range (*[{{.MaxComplex}}]complex128)(unsafe.Pointer(C.COMPLEX(p)))[:n:n] {
+			if C.R_IsNA(C.double(real(c))) != 0 {
+				panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+			}
+			r[i] = c
+		}
+		v = r
+
+	case C.STRSXP:
+		r := make([]string, n)
+		for i := range r {
+			if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+				panic(fmt.Sprintf("NA not allowed for Go dynamic value at index %d", i+1))
+			}
+			r[i] = C.R_gostring(p, C.R_xlen_t(i))
+		}
+		v = r
+
+	case C.RAWSXP:
+		r := make([]byte, n)
+		copy(r, (*[{{.MaxRaw}}]byte)(unsafe.Pointer(C.RAW(p)))[:n:n])
+		return r
+
+	case C.VECSXP:
+		names := C.Rf_getAttrib(p, C.R_NamesSymbol)
+		if isNamedList(names) {
+			r := make(map[string]interface{}, n)
+			for i := 0; i < n; i++ {
+				r[C.R_gostring(names, C.R_xlen_t(i))] = unpackDynamic(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+			}
+			return r
+		}
+		r := make([]interface{}, n)
+		for i := range r {
+			r[i] = unpackDynamic(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+		}
+		return r
+
+	default:
+		panic(fmt.Sprintf("unhandled R type for Go dynamic value: %s", C.GoString(C.Rf_type2char(C.SEXPTYPE(C.R_typeof(p))))))
+	}
+	if n == 1 {
+		return reflect.ValueOf(v).Index(0).Interface()
+	}
+	return v
+}
+
+// isNamedList returns whether the R names vector names has a non-empty
+// name for every element.
+func isNamedList(names C.SEXP) bool {
+	if C.Rf_isString(names) == 0 {
+		return false
+	}
+	for i := C.R_xlen_t(0); i < C.Rf_xlength(names); i++ {
+		if C.STRING_ELT(names, i) == C.R_NaString || C.R_gostring(names, i) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+`))
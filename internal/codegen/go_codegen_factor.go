@@ -0,0 +1,204 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// enumOf returns the enum-like type of typ, or of the elements of typ if
+// it is a slice, and whether typ is a slice. If neither is enum-like, the
+// returned type is nil.
+func enumOf(typ types.Type) (enum types.Type, slice bool) {
+	if s, ok := typ.(*types.Slice); ok {
+		if pkg.Enum(s.Elem()) != nil {
+			return s.Elem(), true
+		}
+		return nil, false
+	}
+	if pkg.Enum(typ) != nil {
+		return typ, false
+	}
+	return nil, false
+}
+
+func packEnum(buf *bytes.Buffer, enum types.Type, slice bool) {
+	if !slice {
+		fmt.Fprintf(buf, "\treturn packSEXP%s([]%s{p})\n", pkg.Mangle(types.NewSlice(enum)), nameOf(enum))
+		return
+	}
+	fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	codes := make([]int32, len(p))
+	for i, v := range p {
+		codes[i] = int32(C.R_NaInt)
+		for j, l := range levels%[1]s.values {
+			if v == l {
+				codes[i] = int32(j + 1)
+				break
+			}
+		}
+	}
+	for i, c := range codes {
+		if C.int(c) == C.R_NaInt {
+			warn := C.CString(fmt.Sprintf("Go %[2]s value %%v at index %%d matches no level and is packed as NA", p[i], i+1))
+			C.R_warning(warn)
+			C.free(unsafe.Pointer(warn))
+			break
+		}
+	}
+	return packFactor(codes, levels%[1]s.labels)
+`, pkg.Mangle(enum), nameOf(enum))
+}
+
+func unpackEnum(buf *bytes.Buffer, enum types.Type, slice bool) {
+	if !slice {
+		fmt.Fprintf(buf, "\treturn unpackSEXP%s(p)[0]\n", pkg.Mangle(types.NewSlice(enum)))
+		return
+	}
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	idx := unpackFactor(p, levels%[1]s.labels, "%[2]s")
+	r := make([]%[2]s, len(idx))
+	for i, j := range idx {
+		r[i] = levels%[1]s.values[j]
+	}
+	return r
+`, pkg.Mangle(enum), nameOf(enum))
+}
+
+// factorHelpers returns the Go source for the functions converting between
+// enum-like values and R factors, and the factor levels of the given
+// enum-like types.
+func factorHelpers(enums []types.Type) string {
+	type level struct {
+		Value string
+		Label string
+	}
+	type enum struct {
+		Name   string
+		Type   string
+		Levels []level
+	}
+	data := struct {
+		Max   int
+		Enums []enum
+	}{}
+	// Maximum length array type for this element type.
+	type a [1 << 47]int32
+	data.Max = len(&a{})
+	for _, typ := range enums {
+		e := enum{Name: pkg.Mangle(typ), Type: nameOf(typ)}
+		stringer := hasStringMethod(typ)
+		for _, c := range pkg.Enum(typ) {
+			value := fmt.Sprintf("%s.%s", c.Pkg().Name(), c.Name())
+			label := fmt.Sprintf("%q", c.Name())
+			if stringer {
+				label = value + ".String()"
+			}
+			e.Levels = append(e.Levels, level{Value: value, Label: label})
+		}
+		data.Enums = append(data.Enums, e)
+	}
+	var buf strings.Builder
+	err := factorHelpersTmpl.Execute(&buf, data)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// hasStringMethod returns whether typ has a String() string method.
+func hasStringMethod(typ types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(typ, false, nil, "String")
+	fn, ok := obj.(*types.Func)
+	if !ok || !fn.Exported() {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Results().At(0).Type(), types.Typ[types.String])
+}
+
+var factorHelpersTmpl = template.Must(template.New("factor").Parse(`// packFactor returns an R factor with the given 1-based codes into
+// levels or NA.
+func packFactor(codes []int32, levels []string) C.SEXP {
+	r := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(len(codes)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	copy((*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(r)))[:len(codes)], codes)
+	lev := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(len(levels)))
+	C.Rf_protect(lev)
+	defer C.Rf_unprotect(1)
+	for i, l := range levels {
+		C.SET_STRING_ELT(lev, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(l), C.int(len(l)), C.CE_UTF8))
+	}
+	C.Rf_setAttrib(r, C.R_LevelsSymbol, lev)
+	cls := C.CString("factor")
+	defer C.free(unsafe.Pointer(cls))
+	class := C.Rf_mkString(cls)
+	C.Rf_protect(class)
+	defer C.Rf_unprotect(1)
+	C.Rf_classgets(r, class)
+	return r
+}
+
+// unpackFactor returns the 0-based indexes into levels of the values in
+// the R factor or character vector p. It panics if p holds NA or a value
+// that is not in levels.
+func unpackFactor(p C.SEXP, levels []string, typ string) []int {
+	index := make(map[string]int, len(levels))
+	for i, l := range levels {
+		index[l] = i
+	}
+	n := C.Rf_xlength(p)
+	r := make([]int, n)
+	if C.Rf_isFactor(p) == 0 {
+		for i := range r {
+			if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+				panic(fmt.Sprintf("NA not allowed for Go %s value at index %d", typ, i+1))
+			}
+			l := C.R_gostring(p, C.R_xlen_t(i))
+			j, ok := index[l]
+			if !ok {
+				panic(fmt.Sprintf("unknown level %q for Go %s value at index %d", l, typ, i+1))
+			}
+			r[i] = j
+		}
+		return r
+	}
+	have := C.Rf_getAttrib(p, C.R_LevelsSymbol)
+	for i, c := range (*[{{.Max}}]int32)(unsafe.Pointer(C.INTEGER(p)))[:n] {
+		if C.int(c) == C.R_NaInt {
+			panic(fmt.Sprintf("NA not allowed for Go %s value at index %d", typ, i+1))
+		}
+		l := C.R_gostring(have, C.R_xlen_t(c-1))
+		j, ok := index[l]
+		if !ok {
+			panic(fmt.Sprintf("unknown level %q for Go %s value at index %d", l, typ, i+1))
+		}
+		r[i] = j
+	}
+	return r
+}
+{{range .Enums}}
+// levels{{.Name}} holds the values and R factor levels of {{.Type}}.
+var levels{{.Name}} = struct {
+	values []{{.Type}}
+	labels []string
+}{
+	values: []{{.Type}}{ {{- range $i, $l := .Levels}}{{if $i}}, {{end}}{{$l.Value}}{{end -}} },
+	labels: []string{ {{- range $i, $l := .Levels}}{{if $i}}, {{end}}{{$l.Label}}{{end -}} },
+}
+{{end}}
+`))
@@ -0,0 +1,228 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// imageConstructor returns the name of the image package function
+// returning images of the given type passed from R.
+func imageConstructor(kind pkg.ImageKind) string {
+	switch kind {
+	case pkg.AnyImage, pkg.NRGBAImage:
+		return "NewNRGBA"
+	case pkg.RGBAImage:
+		return "NewRGBA"
+	case pkg.GrayImage:
+		return "NewGray"
+	default:
+		panic(fmt.Sprintf("unhandled image type: %d", kind))
+	}
+}
+
+func packImage(buf *bytes.Buffer) {
+	fmt.Fprint(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return packImage(p)
+`)
+}
+
+func unpackImage(buf *bytes.Buffer, typ types.Type, kind pkg.ImageKind) {
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	src := unpackImage(p, "%s")
+	r := image.%s(src.Bounds())
+	draw.Draw(r, r.Bounds(), src, image.Point{}, draw.Src)
+	return r
+`, nameOf(typ), imageConstructor(kind))
+}
+
+// imageHelpers returns the Go source for the functions converting between
+// images and R arrays, rasters and nativeRasters. Images are returned to R
+// in the representation given by opts.
+func imageHelpers(opts pkg.Options) string {
+	// Maximum length array type for this element type.
+	type a [1 << 46]float64
+	// Maximum length array type for this element type.
+	type b [1 << 47]int32
+	var buf strings.Builder
+	err := imageHelpersTmpl.Execute(&buf, struct {
+		Max          int
+		MaxInt       int
+		NativeRaster bool
+	}{Max: len(&a{}), MaxInt: len(&b{}), NativeRaster: opts.NativeRaster})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+var imageHelpersTmpl = template.Must(template.New("image").Parse(`{{if .NativeRaster -}}
+// packImage returns an R nativeRaster holding the pixels of p.
+func packImage(p image.Image) C.SEXP {
+	b := p.Bounds()
+	h, w := b.Dy(), b.Dx()
+	r := C.Rf_allocVector(C.INTSXP, C.R_xlen_t(h*w))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(r)))[:h*w]
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(p.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			s[y*w+x] = int32(uint32(c.R) | uint32(c.G)<<8 | uint32(c.B)<<16 | uint32(c.A)<<24)
+		}
+	}
+	setDim(r, int32(h), int32(w))
+	cls := C.CString("nativeRaster")
+	defer C.free(unsafe.Pointer(cls))
+	class := C.Rf_mkString(cls)
+	C.Rf_protect(class)
+	defer C.Rf_unprotect(1)
+	C.Rf_classgets(r, class)
+	return r
+}
+{{- else -}}
+// packImage returns an R double array with dim c(h, w, 4) holding the
+// non-premultiplied red, green, blue and alpha values of the pixels of
+// p, scaled to [0, 1].
+func packImage(p image.Image) C.SEXP {
+	b := p.Bounds()
+	h, w := b.Dy(), b.Dx()
+	n := h * w
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(4*n))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:4*n]
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA64Model.Convert(p.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA64)
+			i := y + h*x
+			s[i] = float64(c.R) / 0xffff
+			s[i+n] = float64(c.G) / 0xffff
+			s[i+2*n] = float64(c.B) / 0xffff
+			s[i+3*n] = float64(c.A) / 0xffff
+		}
+	}
+	setDim(r, int32(h), int32(w), 4)
+	return r
+}
+{{- end}}
+
+// unpackImage returns the image held by the R value p. The value may be
+// a nativeRaster, a raster of hexadecimal colours, or a double array in
+// [0, 1] with dim c(h, w) for grey images, or c(h, w, k) with k channels
+// holding grey, grey and alpha, RGB or RGBA values.
+func unpackImage(p C.SEXP, typ string) *image.NRGBA64 {
+	d := C.Rf_getAttrib(p, C.R_DimSymbol)
+	nd := int(C.Rf_xlength(d))
+	if C.Rf_isInteger(d) == 0 || nd < 2 || nd > 3 {
+		panic(fmt.Sprintf("argument is not an image for Go %s value", typ))
+	}
+	dim := (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(d)))[:nd]
+	h, w := int(dim[0]), int(dim[1])
+	n := h * w
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+
+	native := C.CString("nativeRaster")
+	defer C.free(unsafe.Pointer(native))
+	raster := C.CString("raster")
+	defer C.free(unsafe.Pointer(raster))
+	switch {
+	case C.Rf_inherits(p, native) != 0:
+		if C.Rf_isInteger(p) == 0 || nd != 2 {
+			panic(fmt.Sprintf("invalid nativeRaster for Go %s value", typ))
+		}
+		// nativeRaster pixels are held in row-major order.
+		for i, v := range (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(p)))[:n] {
+			c := uint32(v)
+			img.Set(i%w, i/w, color.NRGBA{R: uint8(c), G: uint8(c >> 8), B: uint8(c >> 16), A: uint8(c >> 24)})
+		}
+	case C.Rf_inherits(p, raster) != 0:
+		if C.Rf_isString(p) == 0 || nd != 2 {
+			panic(fmt.Sprintf("invalid raster for Go %s value", typ))
+		}
+		// raster pixels are held in row-major order.
+		for i := 0; i < n; i++ {
+			if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+				panic(fmt.Sprintf("NA not allowed for Go %s value at index %d", typ, i+1))
+			}
+			s := C.R_gostring(p, C.R_xlen_t(i))
+			c, ok := parseHexColor(s)
+			if !ok {
+				panic(fmt.Sprintf("invalid colour %q for Go %s value at index %d", s, typ, i+1))
+			}
+			img.Set(i%w, i/w, c)
+		}
+	case C.Rf_isReal(p) != 0:
+		k := 1
+		if nd == 3 {
+			k = int(dim[2])
+		}
+		if k < 1 || k > 4 {
+			panic(fmt.Sprintf("image array has %d channels, not 1 to 4, for Go %s value", k, typ))
+		}
+		s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(p)))[:k*n]
+		for i, v := range s {
+			if math.IsNaN(v) {
+				panic(fmt.Sprintf("NA not allowed for Go %s value at index %d", typ, i+1))
+			}
+			if v < 0 || 1 < v {
+				panic(fmt.Sprintf("value %v out of range [0, 1] for Go %s value at index %d", v, typ, i+1))
+			}
+		}
+		at := func(i, c int) uint16 {
+			return uint16(math.Round(s[i+c*n] * 0xffff))
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				i := y + h*x
+				var c color.NRGBA64
+				switch k {
+				case 1:
+					g := at(i, 0)
+					c = color.NRGBA64{R: g, G: g, B: g, A: 0xffff}
+				case 2:
+					g := at(i, 0)
+					c = color.NRGBA64{R: g, G: g, B: g, A: at(i, 1)}
+				case 3:
+					c = color.NRGBA64{R: at(i, 0), G: at(i, 1), B: at(i, 2), A: 0xffff}
+				case 4:
+					c = color.NRGBA64{R: at(i, 0), G: at(i, 1), B: at(i, 2), A: at(i, 3)}
+				}
+				img.SetNRGBA64(x, y, c)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("argument is not an image for Go %s value", typ))
+	}
+	return img
+}
+
+// parseHexColor returns the colour described by the R hexadecimal colour
+// string s, "#RRGGBB" or "#RRGGBBAA", and whether s is a valid colour.
+func parseHexColor(s string) (color.NRGBA, bool) {
+	if len(s) != 7 && len(s) != 9 || s[0] != '#' {
+		return color.NRGBA{}, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.NRGBA{}, false
+	}
+	if len(s) == 7 {
+		v = v<<8 | 0xff
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, true
+}
+
+`))
@@ -0,0 +1,308 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// int64Helpers returns the Go source for the functions converting between
+// 64-bit integer slices and R vectors in the representation given by opts.
+func int64Helpers(opts pkg.Options) string {
+	var tmpl *template.Template
+	switch opts.Int64 {
+	case pkg.Integer64:
+		tmpl = integer64Helpers
+	case pkg.Double:
+		tmpl = doubleInt64Helpers
+	case pkg.Character:
+		tmpl = characterInt64Helpers
+	default:
+		panic(fmt.Sprintf("unhandled int64 representation: %q", opts.Int64))
+	}
+	// Maximum length array type for this element type.
+	type a [1 << 46]float64
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, struct {
+		Max        int
+		NASentinel bool
+	}{Max: len(&a{}), NASentinel: opts.NASentinel})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// int64Suffix returns the name suffix of the 64-bit integer helper
+// functions for the given type.
+func int64Suffix(typ *types.Basic) string {
+	switch typ.Kind() {
+	case types.Int64:
+		return "Int64"
+	case types.Uint64:
+		return "Uint64"
+	default:
+		panic(fmt.Sprintf("unhandled type: %s", typ))
+	}
+}
+
+// intRangeCheck returns Go source that panics if the value v of the basic
+// type typ does not fit in an R integer. The check is only needed for int
+// and uint, which may be 64 bits wide; index is the Go expression for the
+// index of v in a vector, or empty for scalars.
+func intRangeCheck(typ *types.Basic, v, index, indent string) string {
+	var cond string
+	switch typ.Kind() {
+	case types.Int:
+		cond = fmt.Sprintf("%[1]s < -1<<31 || 1<<31-1 < %[1]s", v)
+	case types.Uint:
+		cond = fmt.Sprintf("1<<31-1 < %s", v)
+	default:
+		return ""
+	}
+	if index == "" {
+		return fmt.Sprintf(`%[1]sif %[2]s {
+%[1]s	panic(fmt.Sprintf("value %%d out of range of R integer for Go %[3]s value", %[4]s))
+%[1]s}
+`, indent, cond, typ, v)
+	}
+	return fmt.Sprintf(`%[1]sif %[2]s {
+%[1]s	panic(fmt.Sprintf("value %%d out of range of R integer for Go %[3]s value at index %%d", %[4]s, %[5]s+1))
+%[1]s}
+`, indent, cond, typ, v, index)
+}
+
+var integer64Helpers = template.Must(template.New("integer64").Parse(`// packInt64 returns an R integer64 vector holding the values in p.
+func packInt64(p []int64) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]int64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	copy(s, p)
+	cls := C.CString("integer64")
+	defer C.free(unsafe.Pointer(cls))
+	class := C.Rf_mkString(cls)
+	C.Rf_protect(class)
+	defer C.Rf_unprotect(1)
+	C.Rf_classgets(r, class)
+	return r
+}
+
+// packUint64 returns an R integer64 vector holding the values in p.
+func packUint64(p []uint64) C.SEXP {
+	s := make([]int64, len(p))
+	for i, v := range p {
+		if v > 1<<63-1 {
+			panic(fmt.Sprintf("uint64 value %d overflows integer64", v))
+		}
+		s[i] = int64(v)
+	}
+	return packInt64(s)
+}
+
+// unpackInt64 returns the values held by the R integer64 vector p.
+func unpackInt64(p C.SEXP) []int64 {
+	cls := C.CString("integer64")
+	defer C.free(unsafe.Pointer(cls))
+	if C.Rf_inherits(p, cls) == 0 {
+		panic("argument is not an integer64 vector")
+	}
+	n := C.Rf_xlength(p)
+	r := (*[{{.Max}}]int64)(unsafe.Pointer(C.REAL(p)))[:n]
+{{- if not .NASentinel}}
+	for i, v := range r {
+		if v == -1<<63 {
+			panic(fmt.Sprintf("NA not allowed for Go int64 value at index %d", i+1))
+		}
+	}
+{{- end}}
+	return r
+}
+
+// unpackUint64 returns the values held by the R integer64 vector p.
+func unpackUint64(p C.SEXP) []uint64 {
+	cls := C.CString("integer64")
+	defer C.free(unsafe.Pointer(cls))
+	if C.Rf_inherits(p, cls) == 0 {
+		panic("argument is not an integer64 vector")
+	}
+	n := C.Rf_xlength(p)
+	r := make([]uint64, n)
+	for i, v := range (*[{{.Max}}]int64)(unsafe.Pointer(C.REAL(p)))[:n] {
+		if v == -1<<63 {
+			panic(fmt.Sprintf("NA not allowed for Go uint64 value at index %d", i+1))
+		}
+		if v < 0 {
+			panic(fmt.Sprintf("integer64 value %d overflows uint64", v))
+		}
+		r[i] = uint64(v)
+	}
+	return r
+}
+
+// isNA64 returns whether the first element of the R integer64 vector
+// p is NA.
+func isNA64(p C.SEXP) bool {
+	return *(*int64)(unsafe.Pointer(C.REAL(p))) == -1<<63
+}
+
+`))
+
+var doubleInt64Helpers = template.Must(template.New("double").Parse(`// maxExact is the largest magnitude integer that is exactly
+// represented by a double.
+const maxExact = 1 << 53
+
+// packInt64 returns an R double vector holding the values in p.
+func packInt64(p []int64) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	for i, v := range p {
+		if v < -maxExact || maxExact < v {
+			panic(fmt.Sprintf("int64 value %d cannot be exactly represented as a double", v))
+		}
+		s[i] = float64(v)
+	}
+	return r
+}
+
+// packUint64 returns an R double vector holding the values in p.
+func packUint64(p []uint64) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	for i, v := range p {
+		if maxExact < v {
+			panic(fmt.Sprintf("uint64 value %d cannot be exactly represented as a double", v))
+		}
+		s[i] = float64(v)
+	}
+	return r
+}
+
+// unpackInt64 returns the values held by the R double vector p.
+func unpackInt64(p C.SEXP) []int64 {
+	n := C.Rf_xlength(p)
+	r := make([]int64, n)
+	for i, v := range (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(p)))[:n] {
+		if C.R_IsNA(C.double(v)) != 0 {
+{{- if .NASentinel}}
+			r[i] = -1 << 63
+			continue
+{{- else}}
+			panic(fmt.Sprintf("NA not allowed for Go int64 value at index %d", i+1))
+{{- end}}
+		}
+		if v < -maxExact || maxExact < v || v != float64(int64(v)) {
+			panic(fmt.Sprintf("value %v is not an exact int64 value", v))
+		}
+		r[i] = int64(v)
+	}
+	return r
+}
+
+// unpackUint64 returns the values held by the R double vector p.
+func unpackUint64(p C.SEXP) []uint64 {
+	n := C.Rf_xlength(p)
+	r := make([]uint64, n)
+	for i, v := range (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(p)))[:n] {
+		if C.R_IsNA(C.double(v)) != 0 {
+			panic(fmt.Sprintf("NA not allowed for Go uint64 value at index %d", i+1))
+		}
+		if v < 0 || maxExact < v || v != float64(uint64(v)) {
+			panic(fmt.Sprintf("value %v is not an exact uint64 value", v))
+		}
+		r[i] = uint64(v)
+	}
+	return r
+}
+
+// isNA64 returns whether the first element of the R double vector
+// p is NA.
+func isNA64(p C.SEXP) bool {
+	return C.R_IsNA(*C.REAL(p)) != 0
+}
+
+`))
+
+var characterInt64Helpers = template.Must(template.New("character").Parse(`// packInt64 returns an R character vector holding the decimal
+// values in p.
+func packInt64(p []int64) C.SEXP {
+	r := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	for i, v := range p {
+		s := strconv.FormatInt(v, 10)
+		C.SET_STRING_ELT(r, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(s), C.int(len(s)), C.CE_UTF8))
+	}
+	return r
+}
+
+// packUint64 returns an R character vector holding the decimal
+// values in p.
+func packUint64(p []uint64) C.SEXP {
+	r := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	for i, v := range p {
+		s := strconv.FormatUint(v, 10)
+		C.SET_STRING_ELT(r, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(s), C.int(len(s)), C.CE_UTF8))
+	}
+	return r
+}
+
+// unpackInt64 returns the values held by the R character vector p.
+func unpackInt64(p C.SEXP) []int64 {
+	n := C.Rf_xlength(p)
+	r := make([]int64, n)
+	for i := range r {
+		if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+{{- if .NASentinel}}
+			r[i] = -1 << 63
+			continue
+{{- else}}
+			panic(fmt.Sprintf("NA not allowed for Go int64 value at index %d", i+1))
+{{- end}}
+		}
+		v, err := strconv.ParseInt(C.R_gostring(p, C.R_xlen_t(i)), 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		r[i] = v
+	}
+	return r
+}
+
+// unpackUint64 returns the values held by the R character vector p.
+func unpackUint64(p C.SEXP) []uint64 {
+	n := C.Rf_xlength(p)
+	r := make([]uint64, n)
+	for i := range r {
+		if C.STRING_ELT(p, C.R_xlen_t(i)) == C.R_NaString {
+			panic(fmt.Sprintf("NA not allowed for Go uint64 value at index %d", i+1))
+		}
+		v, err := strconv.ParseUint(C.R_gostring(p, C.R_xlen_t(i)), 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		r[i] = v
+	}
+	return r
+}
+
+// isNA64 returns whether the first element of the R character vector
+// p is NA.
+func isNA64(p C.SEXP) bool {
+	return C.STRING_ELT(p, 0) == C.R_NaString
+}
+
+`))
@@ -0,0 +1,142 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// isNA returns a Go boolean expression that is true if the value held in
+// the expression v, taken from an R vector corresponding to the basic
+// type typ, is NA. For string types, v must be a CHARSXP expression.
+// It returns the empty string if the R type has no NA value.
+func isNA(typ *types.Basic, v string) string {
+	switch typ.Kind() {
+	case types.Bool, types.Int, types.Int8, types.Int16, types.Int32, types.Uint, types.Uint16, types.Uint32:
+		return fmt.Sprintf("C.int(%s) == C.R_NaInt", v)
+	case types.Float32, types.Float64:
+		return fmt.Sprintf("C.R_IsNA(C.double(%s)) != 0", v)
+	case types.Complex64, types.Complex128:
+		return fmt.Sprintf("C.R_IsNA(C.double(real(%s))) != 0", v)
+	case types.String:
+		return fmt.Sprintf("%s == C.R_NaString", v)
+	default:
+		return ""
+	}
+}
+
+// naCheck returns Go source that panics if the value held in the expression
+// v, as described for isNA, is NA. If index is not empty, it is the
+// expression for the zero-based index of the value in its vector. The check
+// is omitted when NA sentinels are allowed, except for logical values since
+// Go bool has no sentinel value.
+func naCheck(opts pkg.Options, typ *types.Basic, v, index, indent string) string {
+	cond := isNA(typ, v)
+	if cond == "" || (opts.NASentinel && typ.Kind() != types.Bool) {
+		return ""
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%sif %s {\n", indent, cond)
+	if index == "" {
+		fmt.Fprintf(&buf, "%s\tpanic(\"NA not allowed for Go %s value\")\n", indent, typ)
+	} else {
+		fmt.Fprintf(&buf, "%s\tpanic(fmt.Sprintf(\"NA not allowed for Go %s value at index %%d\", %s+1))\n", indent, typ, index)
+	}
+	fmt.Fprintf(&buf, "%s}\n", indent)
+	return buf.String()
+}
+
+// naWarning returns Go source that raises an R warning if the Go integer
+// value held in the expression v will be seen as NA by R. If loop is true
+// the source breaks out of the enclosing loop after warning. It returns
+// the empty string if no value of the basic type typ can be seen as NA.
+func naWarning(typ *types.Basic, v, indent string, loop bool) string {
+	switch typ.Kind() {
+	case types.Int, types.Int32, types.Uint, types.Uint32:
+	default:
+		return ""
+	}
+	var brk string
+	if loop {
+		brk = indent + "\tbreak\n"
+	}
+	return fmt.Sprintf(`%[1]sif C.int(%[2]s) == C.R_NaInt {
+%[1]s	warn := C.CString("Go %[3]s value packed as NA")
+%[1]s	C.R_warning(warn)
+%[1]s	C.free(unsafe.Pointer(warn))
+%[4]s%[1]s}
+`, indent, v, typ, brk)
+}
+
+// isNAScalar returns a Go boolean expression that is true if the first
+// element of the R vector p corresponding to the basic type typ is NA. It
+// returns the empty string if the R type has no NA value.
+func isNAScalar(typ *types.Basic) string {
+	switch typ.Kind() {
+	case types.Bool:
+		return isNA(typ, "*C.LOGICAL(p)")
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Uint, types.Uint16, types.Uint32:
+		return isNA(typ, "*C.INTEGER(p)")
+	case types.Float32, types.Float64:
+		return isNA(typ, "*C.REAL(p)")
+	case types.Complex64, types.Complex128:
+		return "C.R_IsNA((*C.COMPLEX(p)).r) != 0"
+	case types.String:
+		return isNA(typ, "C.STRING_ELT(p, 0)")
+	case types.Int64, types.Uint64:
+		return "isNA64(p)"
+	default:
+		return ""
+	}
+}
+
+// naScan returns Go source that panics if any element of the Go slice r,
+// aliasing an R vector with elements corresponding to the basic type typ,
+// is NA. The check is omitted when NA sentinels are allowed.
+func naScan(opts pkg.Options, typ *types.Basic) string {
+	check := naCheck(opts, typ, "v", "i", "\t\t")
+	if check == "" {
+		return ""
+	}
+	return "\tfor i, v := range r {\n" + check + "\t}\n"
+}
+
+// nullableField returns the name of the value field of the database/sql
+// nullable type typ.
+func nullableField(typ *types.Named) string {
+	return strings.TrimPrefix(typ.Obj().Name(), "Null")
+}
+
+// naWarningScan returns Go source that raises an R warning if any element
+// of the Go slice s, holding values converted from the basic type typ, will
+// be seen as NA by R.
+func naWarningScan(typ *types.Basic) string {
+	warn := naWarning(typ, "v", "\t\t", true)
+	if warn == "" {
+		return ""
+	}
+	return "\tfor _, v := range s {\n" + warn + "\t}\n"
+}
+
+// naValue returns a Go expression for an R NA scalar corresponding to the
+// basic type typ.
+func naValue(typ *types.Basic) string {
+	switch typ.Kind() {
+	case types.Bool:
+		return "C.ScalarLogical(C.R_NaInt)"
+	case types.Int32:
+		return "C.ScalarInteger(C.R_NaInt)"
+	case types.Float64:
+		return "C.ScalarReal(C.R_NaReal)"
+	case types.String:
+		return "C.ScalarString(C.R_NaString)"
+	default:
+		panic(fmt.Sprintf("unhandled type: %s", typ))
+	}
+}
@@ -8,17 +8,18 @@ import (
 	"bytes"
 	"fmt"
 	"go/types"
+	"strings"
 
 	"github.com/rgonomic/rgo/internal/pkg"
 )
 
 // packSEXPFuncGo returns the source of functions to pack the given Go-typed
 // parameters into R SEXP values.
-func packSEXPFuncGo(typs []types.Type) string {
+func packSEXPFuncGo(opts pkg.Options, typs []types.Type) string {
 	var buf bytes.Buffer
 	for _, typ := range typs {
 		fmt.Fprintf(&buf, "func packSEXP%s(p %s) C.SEXP {\n", pkg.Mangle(typ), nameOf(typ))
-		packSEXPFuncBodyGo(&buf, typ)
+		packSEXPFuncBodyGo(&buf, typ, opts)
 		buf.WriteString("}\n\n")
 	}
 	return buf.String()
@@ -26,10 +27,54 @@ func packSEXPFuncGo(typs []types.Type) string {
 
 // packSEXPFuncGo returns the body of a function to pack the given Go-typed
 // parameters into R SEXP values.
-func packSEXPFuncBodyGo(buf *bytes.Buffer, typ types.Type) {
+func packSEXPFuncBodyGo(buf *bytes.Buffer, typ types.Type, opts pkg.Options) {
+	if kind := pkg.Matrix(typ); kind != pkg.NotMatrix {
+		packMatrix(buf, typ, kind)
+		return
+	}
+	if kind := opts.Frame(typ); kind != pkg.NotFrame {
+		packFrame(buf, typ, kind, opts)
+		return
+	}
+	if kind, slice := temporalOf(opts, typ); kind != pkg.NotTemporal {
+		packTemporal(buf, typ, kind, slice)
+		return
+	}
+	if kind, ptr, slice := pkg.BigOf(typ); kind != pkg.NotBig {
+		packBig(buf, kind, ptr, slice)
+		return
+	}
+	if pkg.Image(typ) != pkg.NotImage {
+		packImage(buf)
+		return
+	}
+	if kind := pkg.Arrow(typ); kind != pkg.NotArrow {
+		packArrow(buf, kind)
+		return
+	}
+	if pkg.IsSEXP(typ) {
+		fmt.Fprintln(buf, "\treturn C.SEXP(p.Pointer())")
+		return
+	}
+	if kind, slice := textOf(opts, typ); kind != pkg.NotText {
+		packText(buf, kind, slice)
+		return
+	}
+	if enum, slice := enumOf(typ); enum != nil {
+		packEnum(buf, enum, slice)
+		return
+	}
+	if elem := pkg.Nullable(typ); elem != nil {
+		packNullable(buf, typ.(*types.Named), elem)
+		return
+	}
+	if pkg.IsDynamic(typ) {
+		packDynamic(buf)
+		return
+	}
 	switch typ := typ.(type) {
 	case *types.Named:
-		packNamed(buf, typ)
+		packNamed(buf, typ, opts)
 
 	case *types.Array:
 		packArray(buf, typ)
@@ -38,40 +83,87 @@ func packSEXPFuncBodyGo(buf *bytes.Buffer, typ types.Type) {
 		packBasic(buf, typ)
 
 	case *types.Map:
-		packMap(buf, typ)
+		packMap(buf, typ, opts)
 
 	case *types.Pointer:
-		packPointer(buf, typ)
+		packPointer(buf, typ, opts)
 
 	case *types.Slice:
 		packSlice(buf, typ)
 
 	case *types.Struct:
-		packStruct(buf, typ)
+		packStruct(buf, typ, opts, "")
 
 	default:
 		panic(fmt.Sprintf("unhandled type: %s", typ))
 	}
 }
 
-func packNamed(buf *bytes.Buffer, typ *types.Named) {
+func packNamed(buf *bytes.Buffer, typ *types.Named, opts pkg.Options) {
 	if pkg.IsError(typ) {
 		fmt.Fprintf(buf, `	if p == nil {
 		return C.R_NilValue
 	}
 	return packSEXP%s(p.Error())
 `, pkg.Mangle(types.Typ[types.String]))
-	} else {
-		switch typ := typ.Underlying().(type) {
-		case *types.Array, *types.Map, *types.Pointer, *types.Slice, *types.Struct:
-			fmt.Fprintf(buf, "\treturn packSEXP%s(p)\n", pkg.Mangle(typ))
-		default:
-			fmt.Fprintf(buf, "\treturn packSEXP%s(%s(p))\n", pkg.Mangle(typ), typ)
+		return
+	}
+	if impls := pkg.Union(typ); impls != nil {
+		packUnion(buf, typ, impls, opts)
+		return
+	}
+	class := opts.Class(typ)
+	var val string
+	switch under := typ.Underlying().(type) {
+	case *types.Struct:
+		if opts.SkipsFields(under) {
+			// The unnamed struct type cannot be written
+			// without the skipped fields.
+			packStruct(buf, under, opts, class)
+			return
 		}
+		val = fmt.Sprintf("packSEXP%s(p)", pkg.Mangle(under))
+	case *types.Array, *types.Map, *types.Pointer, *types.Slice:
+		val = fmt.Sprintf("packSEXP%s(p)", pkg.Mangle(under))
+	default:
+		val = fmt.Sprintf("packSEXP%s(%s(p))", pkg.Mangle(under), under)
+	}
+	if class == "" {
+		fmt.Fprintf(buf, "\treturn %s\n", val)
+		return
+	}
+	fmt.Fprintf(buf, `	r := %s
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+`, val)
+	_, _, nilable := rTypeOf(opts, typ)
+	packClass(buf, class, nilable)
+	fmt.Fprintln(buf, "\treturn r")
+}
+
+// packClass writes the Go source for adding the S3 class to the classes
+// of r. If nilable is true, r may be NULL.
+func packClass(buf *bytes.Buffer, class string, nilable bool) {
+	indent := "\t"
+	if nilable {
+		// Attributes cannot be set on NULL.
+		fmt.Fprintln(buf, "\tif C.Rf_isNull(r) == 0 {")
+		indent = "\t\t"
+	}
+	fmt.Fprintf(buf, `%[1]scls := C.CString(%[2]q)
+%[1]sdefer C.free(unsafe.Pointer(cls))
+%[1]sC.R_addClass(r, cls)
+`, indent, class)
+	if nilable {
+		fmt.Fprintln(buf, "\t}")
 	}
 }
 
 func packArray(buf *bytes.Buffer, typ *types.Array) {
+	if dims, elem := pkg.ArrayDims(typ); dims != nil {
+		packNestedArray(buf, dims, elem)
+		return
+	}
 	fmt.Fprintf(buf, "\treturn packSEXP%s(p[:])\n", pkg.Mangle(types.NewSlice(typ.Elem())))
 }
 
@@ -84,8 +176,12 @@ func packBasic(buf *bytes.Buffer, typ *types.Basic) {
 	}
 	return C.ScalarLogical(b)
 `)
-	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64, types.Uint, types.Uint16, types.Uint32, types.Uint64:
-		fmt.Fprintln(buf, "\treturn C.ScalarInteger(C.int(p))")
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Uint, types.Uint16, types.Uint32:
+		fmt.Fprintf(buf, "%s%s\treturn C.ScalarInteger(C.int(p))\n", intRangeCheck(typ, "p", "", "\t"), naWarning(typ, "p", "\t", false))
+	case types.Int64:
+		fmt.Fprintln(buf, "\treturn packInt64([]int64{p})")
+	case types.Uint64:
+		fmt.Fprintln(buf, "\treturn packUint64([]uint64{p})")
 	case types.Uint8:
 		fmt.Fprintln(buf, "\treturn C.ScalarRaw(C.Rbyte(p))")
 	case types.Float64, types.Float32:
@@ -102,10 +198,28 @@ func packBasic(buf *bytes.Buffer, typ *types.Basic) {
 	}
 }
 
-func packMap(buf *bytes.Buffer, typ *types.Map) {
+func packMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
 	fmt.Fprintln(buf, `	if p == nil {
 		return C.R_NilValue
 	}`)
+	switch pkg.Map(typ) {
+	case pkg.KeyedMap:
+		packKeyedMap(buf, typ, opts)
+		return
+	case pkg.SetMap:
+		packSetMap(buf, typ, opts)
+		return
+	}
+	if !types.Identical(typ.Key(), types.Typ[types.String]) {
+		m := types.NewMap(types.Typ[types.String], typ.Elem())
+		fmt.Fprintf(buf, `	m := make(%s, len(p))
+	for k, v := range p {
+		m[string(k)] = v
+	}
+	return packSEXP%s(m)
+`, nameOf(m), pkg.Mangle(m))
+		return
+	}
 	// TODO(kortschak): Handle named simple types properly.
 	elem := typ.Elem()
 	if basic, ok := elem.Underlying().(*types.Basic); ok {
@@ -125,13 +239,33 @@ func packMap(buf *bytes.Buffer, typ *types.Map) {
 	s := (*[%[2]d]int32)(unsafe.Pointer(C.INTEGER(r)))[:len(p):len(p)]
 	var i C.R_xlen_t
 	for k, v := range p {
-		C.SET_STRING_ELT(names, i, C.Rf_mkCharLenCE(C._GoStringPtr(k), C.int(len(k)), C.CE_UTF8))
+%[4]s		C.SET_STRING_ELT(names, i, C.Rf_mkCharLenCE(C._GoStringPtr(k), C.int(len(k)), C.CE_UTF8))
 		s[i] = int32(v)
 		i++
 	}
+%[3]s	C.setAttrib(r, C.R_NamesSymbol, names)
+	return r
+`, rTypeLabelFor(elem), len(&a{}), naWarningScan(basic), intRangeCheck(basic, "v", "i", "\t\t"))
+			return
+
+		case types.Int64, types.Uint64:
+			fmt.Fprintf(buf, `	n := len(p)
+	names := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(n))
+	C.Rf_protect(names)
+	defer C.Rf_unprotect(1)
+	s := make([]%[1]s, 0, n)
+	var i C.R_xlen_t
+	for k, v := range p {
+		C.SET_STRING_ELT(names, i, C.Rf_mkCharLenCE(C._GoStringPtr(k), C.int(len(k)), C.CE_UTF8))
+		s = append(s, %[1]s(v))
+		i++
+	}
+	r := pack%[2]s(s)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
 	C.setAttrib(r, C.R_NamesSymbol, names)
 	return r
-`, rTypeLabelFor(elem), len(&a{}))
+`, basic, int64Suffix(basic))
 			return
 
 		case types.Uint8:
@@ -297,7 +431,69 @@ func packMap(buf *bytes.Buffer, typ *types.Map) {
 	}
 }
 
-func packPointer(buf *bytes.Buffer, typ *types.Pointer) {
+func packKeyedMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
+	keys := opts.MapSlice(typ.Key())
+	values := opts.MapSlice(typ.Elem())
+	fmt.Fprintf(buf, `	keys := make(%s, 0, len(p))
+	values := make(%s, 0, len(p))
+	for k, v := range p {
+		keys = append(keys, %s)
+		values = append(values, %s)
+	}`, nameOf(keys), nameOf(values), convert(keys.Elem(), typ.Key(), "k"), convert(values.Elem(), typ.Elem(), "v"))
+	fmt.Fprintf(buf, `
+	r := C.Rf_allocVector(C.VECSXP, 2)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	names := C.Rf_allocVector(C.STRSXP, 2)
+	C.Rf_protect(names)
+	defer C.Rf_unprotect(1)
+	C.SET_STRING_ELT(names, 0, C.Rf_mkCharLenCE(C._GoStringPtr("keys"), 4, C.CE_UTF8))
+	C.SET_VECTOR_ELT(r, 0, packSEXP%s(keys))
+	C.SET_STRING_ELT(names, 1, C.Rf_mkCharLenCE(C._GoStringPtr("values"), 6, C.CE_UTF8))
+	C.SET_VECTOR_ELT(r, 1, packSEXP%s(values))
+	C.setAttrib(r, C.R_NamesSymbol, names)
+	return r
+`, pkg.Mangle(keys), pkg.Mangle(values))
+}
+
+func packSetMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
+	keys := opts.MapSlice(typ.Key())
+	fmt.Fprintf(buf, "\tkeys := make(%s, 0, len(p))\n", nameOf(keys))
+	key := convert(keys.Elem(), typ.Key(), "k")
+	if _, ok := typ.Elem().Underlying().(*types.Basic); ok {
+		fmt.Fprintf(buf, `	for k, ok := range p {
+		if ok {
+			keys = append(keys, %s)
+		}
+	}
+`, key)
+	} else {
+		fmt.Fprintf(buf, `	for k := range p {
+		keys = append(keys, %s)
+	}
+`, key)
+	}
+	fmt.Fprintf(buf, "\treturn packSEXP%s(keys)\n", pkg.Mangle(keys))
+}
+
+// convert returns the Go expression converting expr of type from to the
+// type to, or expr if the types are identical.
+func convert(to, from types.Type, expr string) string {
+	if types.Identical(to, from) {
+		return expr
+	}
+	return fmt.Sprintf("%s(%s)", nameOf(to), expr)
+}
+
+func packPointer(buf *bytes.Buffer, typ *types.Pointer, opts pkg.Options) {
+	if opts.IsHandle(typ) {
+		fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return packHandle(p, %q)
+`, nameOf(typ.Elem()))
+		return
+	}
 	fmt.Fprintf(buf, `	if p == nil {
 		return C.R_NilValue
 	}
@@ -305,6 +501,14 @@ func packPointer(buf *bytes.Buffer, typ *types.Pointer) {
 `, pkg.Mangle(typ.Elem()))
 }
 
+func packNullable(buf *bytes.Buffer, typ *types.Named, elem *types.Basic) {
+	fmt.Fprintf(buf, `	if !p.Valid {
+		return %s
+	}
+	return packSEXP%s(p.%s)
+`, naValue(elem), pkg.Mangle(elem), nullableField(typ))
+}
+
 func packSlice(buf *bytes.Buffer, typ *types.Slice) {
 	fmt.Fprintln(buf, `	if p == nil {
 		return C.R_NilValue
@@ -323,8 +527,8 @@ func packSlice(buf *bytes.Buffer, typ *types.Slice) {
 	defer C.Rf_unprotect(1)
 	s := (*[%d]%s)(unsafe.Pointer(C.INTEGER(r)))[:len(p)]
 	copy(s, p)
-	return r
-`, len(&a{}), nameOf(elem))
+%s	return r
+`, len(&a{}), nameOf(elem), naWarningScan(elem))
 			return
 		case types.Int, types.Int16, types.Uint, types.Uint16:
 			// Maximum length array type for this element type.
@@ -334,10 +538,16 @@ func packSlice(buf *bytes.Buffer, typ *types.Slice) {
 	defer C.Rf_unprotect(1)
 	s := (*[%[1]d]%[2]s)(unsafe.Pointer(C.INTEGER(r)))[:len(p)]
 	for i, v := range p {
-		s[i] = %[2]s(v)
+%[4]s		s[i] = %[2]s(v)
 	}
-	return r
-`, len(&a{}), nameOf(types.Typ[types.Int32]))
+%[3]s	return r
+`, len(&a{}), nameOf(types.Typ[types.Int32]), naWarningScan(elem), intRangeCheck(elem, "v", "i", "\t\t"))
+			return
+		case types.Int64:
+			fmt.Fprintln(buf, "\treturn packInt64(p)")
+			return
+		case types.Uint64:
+			fmt.Fprintln(buf, "\treturn packUint64(p)")
 			return
 		case types.Int8, types.Uint8:
 			// Maximum length array type for this element type.
@@ -463,27 +673,298 @@ func packSlice(buf *bytes.Buffer, typ *types.Slice) {
 	}
 }
 
-func packStruct(buf *bytes.Buffer, typ *types.Struct) {
-	n := typ.NumFields()
+func packStruct(buf *bytes.Buffer, typ *types.Struct, opts pkg.Options, class string) {
+	var fields, attrs []pkg.Field
+	for _, f := range opts.Fields(typ) {
+		if f.Attr {
+			attrs = append(attrs, f)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	if payload := opts.Payload(typ); payload != nil {
+		fmt.Fprintf(buf, `	r := packSEXP%s(p.%s)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+`, pkg.Mangle(payload.Var.Type()), payload.Path)
+		if _, _, nilable := rTypeOf(opts, payload.Var.Type()); nilable {
+			// Attributes cannot be set on NULL.
+			fmt.Fprint(buf, `	if C.Rf_isNull(r) != 0 {
+		return r
+	}
+`)
+		}
+	} else {
+		packList(buf, fields)
+	}
+	for _, f := range attrs {
+		cond := present(f)
+		indent := "\t"
+		if cond != "" {
+			fmt.Fprintf(buf, "\tif %s {\n", cond)
+			indent = "\t\t"
+		}
+		fmt.Fprintf(buf, `%[1]sattr_%[2]s := packSEXP%[3]s(p.%[4]s)
+%[1]sC.Rf_protect(attr_%[2]s)
+%[1]sdefer C.Rf_unprotect(1)
+%[1]skey_%[2]s := C.CString("%[2]s")
+%[1]sdefer C.free(unsafe.Pointer(key_%[2]s))
+%[1]sC.Rf_setAttrib(r, C.Rf_install(key_%[2]s), attr_%[2]s)
+`, indent, f.Name, pkg.Mangle(f.Var.Type()), f.Path)
+		if cond != "" {
+			fmt.Fprintln(buf, "\t}")
+		}
+	}
+	if class != "" {
+		packClass(buf, class, false)
+	}
+	fmt.Fprintln(buf, "\treturn r")
+}
+
+// packList writes the Go source for packing the given fields of p into
+// a named R list, r.
+func packList(buf *bytes.Buffer, fields []pkg.Field) {
+	var required int
+	for _, f := range fields {
+		if present(f) == "" {
+			required++
+		}
+	}
+	if required == len(fields) {
+		fmt.Fprintf(buf, `	r := C.Rf_allocVector(C.VECSXP, %[1]d)
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	names := C.Rf_allocVector(C.STRSXP, %[1]d)
+	C.Rf_protect(names)
+	defer C.Rf_unprotect(1)
+`, len(fields))
+		for i, f := range fields {
+			fmt.Fprintf(buf, `	C.SET_STRING_ELT(names, %[1]d, C.Rf_mkCharLenCE(C._GoStringPtr("%[2]s"), %[3]d, C.CE_UTF8))
+	C.SET_VECTOR_ELT(r, %[1]d, packSEXP%[4]s(p.%[5]s))
+`, i, f.Name, len(f.Name), pkg.Mangle(f.Var.Type()), f.Path)
+		}
+		fmt.Fprintln(buf, "\tC.setAttrib(r, C.R_NamesSymbol, names)")
+		return
+	}
+
+	// Empty omitempty fields and fields behind nil struct
+	// pointers are not included, so the length of the list
+	// is only known at run time.
+	fmt.Fprintf(buf, "\tn := %d\n", required)
+	for _, f := range fields {
+		if cond := present(f); cond != "" {
+			fmt.Fprintf(buf, "\tif %s {\n\t\tn++\n\t}\n", cond)
+		}
+	}
+	fmt.Fprint(buf, `	r := C.Rf_allocVector(C.VECSXP, C.R_xlen_t(n))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	names := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(n))
+	C.Rf_protect(names)
+	defer C.Rf_unprotect(1)
+	var i C.R_xlen_t
+`)
+	for _, f := range fields {
+		cond := present(f)
+		indent := "\t"
+		if cond != "" {
+			fmt.Fprintf(buf, "\tif %s {\n", cond)
+			indent = "\t\t"
+		}
+		fmt.Fprintf(buf, `%[1]sC.SET_STRING_ELT(names, i, C.Rf_mkCharLenCE(C._GoStringPtr("%[2]s"), %[3]d, C.CE_UTF8))
+%[1]sC.SET_VECTOR_ELT(r, i, packSEXP%[4]s(p.%[5]s))
+%[1]si++
+`, indent, f.Name, len(f.Name), pkg.Mangle(f.Var.Type()), f.Path)
+		if cond != "" {
+			fmt.Fprintln(buf, "\t}")
+		}
+	}
+	fmt.Fprintln(buf, "\tC.setAttrib(r, C.R_NamesSymbol, names)")
+}
+
+// present returns a Go expression that is true when the field f of p is
+// packed, or the empty string if it always is. Fields flattened through
+// nil struct pointers and empty omitempty fields are not packed.
+func present(f pkg.Field) string {
+	var conds []string
+	for _, v := range f.Via {
+		conds = append(conds, "p."+v.Path+" != nil")
+	}
+	if cond := notEmpty("p."+f.Path, f.Var.Type(), f.OmitEmpty); cond != "" {
+		conds = append(conds, cond)
+	}
+	return strings.Join(conds, " && ")
+}
+
+// notEmpty returns a Go expression that is true when the value of expr,
+// of type typ, is not empty as defined for the omitempty struct tag
+// option. It returns the empty string if omitempty is false or values of
+// typ are never empty; structs are never empty.
+func notEmpty(expr string, typ types.Type, omitempty bool) string {
+	if !omitempty {
+		return ""
+	}
+	switch typ := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case typ.Info()&types.IsBoolean != 0:
+			return expr
+		case typ.Info()&types.IsString != 0:
+			return expr + ` != ""`
+		case typ.Kind() == types.UnsafePointer:
+			return expr + " != nil"
+		default:
+			return expr + " != 0"
+		}
+	case *types.Array:
+		if typ.Len() == 0 {
+			return "false"
+		}
+	case *types.Map, *types.Slice:
+		return "len(" + expr + ") != 0"
+	case *types.Interface, *types.Pointer, *types.Signature:
+		return expr + " != nil"
+	}
+	return ""
+}
+
+func packFrame(buf *bytes.Buffer, typ types.Type, kind pkg.FrameKind, opts pkg.Options) {
+	cols := opts.FrameColumns(typ)
+	var fields []pkg.Field
+	switch kind {
+	case pkg.RowFrame:
+		fields = opts.Fields(typ.(*types.Slice).Elem().Underlying().(*types.Struct))
+		fmt.Fprint(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	n := len(p)
+`)
+		for i, col := range cols {
+			fmt.Fprintf(buf, "\tcol%d := make(%s, n)\n", i, nameOf(col))
+		}
+		fmt.Fprintln(buf, "\tfor i, v := range p {")
+		for i, col := range cols {
+			fmt.Fprintf(buf, "\t\tcol%d[i] = %s(v.%s)\n", i, nameOf(col.Elem()), fields[i].Path)
+		}
+		fmt.Fprintln(buf, "\t}")
+	case pkg.ColumnFrame:
+		fields = opts.Fields(typ.(*types.Struct))
+		fmt.Fprintf(buf, "\tn := len(p.%s)\n", fields[0].Path)
+		for i, f := range fields {
+			if i != 0 {
+				fmt.Fprintf(buf, `	if len(p.%[1]s) != n {
+		panic(fmt.Sprintf("data.frame column %[2]s has length %%d, want %%d", len(p.%[1]s), n))
+	}
+`, f.Path, f.Name)
+			}
+			fmt.Fprintf(buf, "\tcol%d := p.%s\n", i, f.Path)
+			fmt.Fprintf(buf, `	if col%[1]d == nil {
+		col%[1]d = %[2]s{}
+	}
+`, i, nameOf(f.Var.Type()))
+		}
+	default:
+		panic(fmt.Sprintf("unhandled data.frame kind: %d", kind))
+	}
+
 	fmt.Fprintf(buf, `	r := C.Rf_allocVector(C.VECSXP, %[1]d)
 	C.Rf_protect(r)
 	defer C.Rf_unprotect(1)
 	names := C.Rf_allocVector(C.STRSXP, %[1]d)
 	C.Rf_protect(names)
 	defer C.Rf_unprotect(1)
-`, n)
-	for i := 0; i < n; i++ {
-		f := typ.Field(i)
-		rName := targetFieldName(typ, i)
-		elem := f.Type()
+`, len(cols))
+	for i, f := range fields {
+		var typ types.Type = cols[i]
+		if kind == pkg.ColumnFrame {
+			typ = f.Var.Type()
+		}
 		fmt.Fprintf(buf, `	C.SET_STRING_ELT(names, %[1]d, C.Rf_mkCharLenCE(C._GoStringPtr("%[2]s"), %[3]d, C.CE_UTF8))
-	C.SET_VECTOR_ELT(r, %[1]d, packSEXP%[4]s(p.%[5]s))
-`, i, rName, len(rName), pkg.Mangle(elem), f.Name())
+	C.SET_VECTOR_ELT(r, %[1]d, packSEXP%[4]s(col%[1]d))
+`, i, f.Name, len(f.Name), pkg.Mangle(typ))
 	}
 	fmt.Fprintln(buf, `	C.setAttrib(r, C.R_NamesSymbol, names)
+	C.R_setDataFrame(r, C.int(n))
 	return r`)
 }
 
+func packMatrix(buf *bytes.Buffer, typ types.Type, kind pkg.MatrixKind) {
+	elem := pkg.MatrixElem(typ)
+	var (
+		sexp, data string
+		size       int
+	)
+	switch elem.Kind() {
+	case types.Int32:
+		// Maximum length array type for this element type.
+		type a [1 << 47]int32
+		sexp, data, size = "INTSXP", "INTEGER", len(&a{})
+	case types.Float64:
+		// Maximum length array type for this element type.
+		type a [1 << 46]float64
+		sexp, data, size = "REALSXP", "REAL", len(&a{})
+	default:
+		panic(fmt.Sprintf("unhandled matrix element type: %s", elem))
+	}
+
+	if kind == pkg.SliceMatrix {
+		fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	rows := len(p)
+	var cols int
+	if rows != 0 {
+		cols = len(p[0])
+	}
+	r := C.Rf_allocMatrix(C.%[1]s, C.int(rows), C.int(cols))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[%[3]d]%[4]s)(unsafe.Pointer(C.%[2]s(r)))[:rows*cols]
+	for i, row := range p {
+		if len(row) != cols {
+			panic("ragged matrix rows")
+		}
+		for j, v := range row {
+			s[i+j*rows] = v
+		}
+	}
+	return r
+`, sexp, data, size, nameOf(elem))
+		return
+	}
+
+	// m is the blas64.General or blas64.GeneralCols holding the data.
+	m := "p"
+	if kind == pkg.DenseMatrix {
+		fmt.Fprintln(buf, "\tm := p.RawMatrix()")
+		m = "m"
+	}
+	fmt.Fprintf(buf, `	r := C.Rf_allocMatrix(C.%[1]s, C.int(%[5]s.Rows), C.int(%[5]s.Cols))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[%[3]d]%[4]s)(unsafe.Pointer(C.%[2]s(r)))[:%[5]s.Rows*%[5]s.Cols]
+`, sexp, data, size, nameOf(elem), m)
+	if kind == pkg.GeneralColsMatrix {
+		fmt.Fprintf(buf, `	if %[1]s.Stride == %[1]s.Rows {
+		copy(s, %[1]s.Data)
+		return r
+	}
+	for j := 0; j < %[1]s.Cols; j++ {
+		copy(s[j*%[1]s.Rows:(j+1)*%[1]s.Rows], %[1]s.Data[j*%[1]s.Stride:])
+	}
+	return r
+`, m)
+		return
+	}
+	fmt.Fprintf(buf, `	for i := 0; i < %[1]s.Rows; i++ {
+		for j, v := range %[1]s.Data[i*%[1]s.Stride : i*%[1]s.Stride+%[1]s.Cols] {
+			s[i+j*%[1]s.Rows] = v
+		}
+	}
+	return r
+`, m)
+}
+
 var typeLabelTable = map[string]string{
 	"logical":   "LGLSXP",
 	"integer":   "INTSXP",
@@ -497,7 +978,7 @@ var typeLabelTable = map[string]string{
 // rTypeLabelFor returns the R type label for the R atomic type
 // corresponding to typ.
 func rTypeLabelFor(typ types.Type) string {
-	name, _, _ := rTypeOf(typ)
+	name, _, _ := rTypeOf(pkg.Options{}, typ)
 	label, ok := typeLabelTable[name]
 	if !ok {
 		return fmt.Sprintf("<%s>", typ)
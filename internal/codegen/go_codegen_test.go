@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"go/constant"
+	"go/token"
 	"go/types"
 	"io/ioutil"
 	"path/filepath"
@@ -22,12 +24,76 @@ import (
 
 var regenerate = flag.Bool("regen", false, "regenerate golden data from current state")
 
-var mockPkg = types.NewPackage("path/to/pkg", "pkg")
+var (
+	mockPkg    = types.NewPackage("path/to/pkg", "pkg")
+	mockBlas64 = types.NewPackage("gonum.org/v1/gonum/blas/blas64", "blas64")
+	mockMat    = types.NewPackage("gonum.org/v1/gonum/mat", "mat")
+	mockSQL    = types.NewPackage("database/sql", "sql")
+	mockTime   = types.NewPackage("time", "time")
+	mockCivil  = types.NewPackage("cloud.google.com/go/civil", "civil")
+	mockSEXP   = types.NewPackage("github.com/rgonomic/rgo/sexp", "sexp")
+)
+
+// mockGeneral is the underlying type of the gonum blas64.General and
+// blas64.GeneralCols types.
+var mockGeneral = types.NewStruct([]*types.Var{
+	types.NewField(0, mockBlas64, "Rows", types.Typ[types.Int], false),
+	types.NewField(0, mockBlas64, "Cols", types.Typ[types.Int], false),
+	types.NewField(0, mockBlas64, "Data", types.NewSlice(types.Typ[types.Float64]), false),
+	types.NewField(0, mockBlas64, "Stride", types.Typ[types.Int], false),
+}, nil)
+
+// mockNullable returns a database/sql nullable type with the given name
+// holding a value of type typ in the named field.
+func mockNullable(name, field string, typ types.Type) types.Type {
+	return types.NewNamed(types.NewTypeName(0, mockSQL, name, nil), types.NewStruct([]*types.Var{
+		types.NewField(0, mockSQL, field, typ, false),
+		types.NewField(0, mockSQL, "Valid", types.Typ[types.Bool], false),
+	}, nil), nil)
+}
+
+var (
+	mockTimeType = types.NewNamed(types.NewTypeName(0, mockTime, "Time", nil), types.NewStruct([]*types.Var{
+		types.NewField(0, mockTime, "wall", types.Typ[types.Uint64], false),
+	}, nil), nil)
+	mockDuration = types.NewNamed(types.NewTypeName(0, mockTime, "Duration", nil), types.Typ[types.Int64], nil)
+	mockMonth    = types.NewNamed(types.NewTypeName(0, mockTime, "Month", nil), types.Typ[types.Int], nil)
+
+	// mockDate has the layout of the cloud.google.com/go/civil.Date type.
+	mockDate = types.NewNamed(types.NewTypeName(0, mockCivil, "Date", nil), types.NewStruct([]*types.Var{
+		types.NewField(0, mockCivil, "Year", types.Typ[types.Int], false),
+		types.NewField(0, mockCivil, "Month", mockMonth, false),
+		types.NewField(0, mockCivil, "Day", types.Typ[types.Int], false),
+	}, nil), nil)
+)
+
+// mockEnum returns an enum-like type in mockPkg with the given name and
+// underlying type, and constants with the given names and values.
+func mockEnum(name string, typ types.Type, consts map[string]constant.Value, order ...string) types.Type {
+	named := types.NewNamed(types.NewTypeName(0, mockPkg, name, nil), typ, nil)
+	for i, c := range order {
+		mockPkg.Scope().Insert(types.NewConst(token.Pos(i+1), mockPkg, c, named, consts[c]))
+	}
+	return named
+}
+
+var (
+	mockColor = mockEnum("Color", types.Typ[types.Int], map[string]constant.Value{
+		"Red":   constant.MakeInt64(0),
+		"Green": constant.MakeInt64(1),
+		"Blue":  constant.MakeInt64(2),
+	}, "Red", "Green", "Blue")
+	mockShape = mockEnum("Shape", types.Typ[types.String], map[string]constant.Value{
+		"Circle": constant.MakeString("circle"),
+		"Square": constant.MakeString("square"),
+	}, "Circle", "Square")
+)
 
 // builtin byte and rune aliases are included, but will not be seen
 // in normal use since the package analysis resolves these away.
 var sexpFuncGoTests = []struct {
-	typ types.Type
+	typ  types.Type
+	opts pkg.Options
 }{
 	// Basic types.
 	{typ: types.Typ[types.String]},
@@ -38,6 +104,8 @@ var sexpFuncGoTests = []struct {
 	{typ: types.Typ[types.Float64]},
 	{typ: types.Typ[types.Complex128]},
 	{typ: types.Typ[types.Bool]},
+	{typ: types.Typ[types.Int64]},
+	{typ: types.Typ[types.Uint64]},
 
 	// Pointer types.
 	{typ: types.NewPointer(types.Typ[types.String])},
@@ -48,6 +116,8 @@ var sexpFuncGoTests = []struct {
 	{typ: types.NewPointer(types.Typ[types.Float64])},
 	{typ: types.NewPointer(types.Typ[types.Complex128])},
 	{typ: types.NewPointer(types.Typ[types.Bool])},
+	{typ: types.NewPointer(types.Typ[types.Int64])},
+	{typ: types.NewPointer(types.Typ[types.Uint64])},
 
 	// Array types.
 	{typ: types.NewArray(types.Typ[types.String], 10)},
@@ -58,6 +128,8 @@ var sexpFuncGoTests = []struct {
 	{typ: types.NewArray(types.Typ[types.Float64], 10)},
 	{typ: types.NewArray(types.Typ[types.Complex128], 10)},
 	{typ: types.NewArray(types.Typ[types.Bool], 10)},
+	{typ: types.NewArray(types.Typ[types.Int64], 10)},
+	{typ: types.NewArray(types.Typ[types.Uint64], 10)},
 
 	// Slice types.
 	{typ: types.NewSlice(types.Typ[types.String])},
@@ -68,6 +140,8 @@ var sexpFuncGoTests = []struct {
 	{typ: types.NewSlice(types.Typ[types.Float64])},
 	{typ: types.NewSlice(types.Typ[types.Complex128])},
 	{typ: types.NewSlice(types.Typ[types.Bool])},
+	{typ: types.NewSlice(types.Typ[types.Int64])},
+	{typ: types.NewSlice(types.Typ[types.Uint64])},
 
 	// Map types.
 	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.String])},
@@ -78,6 +152,90 @@ var sexpFuncGoTests = []struct {
 	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.Float64])},
 	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.Complex128])},
 	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.Bool])},
+	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.Int64])},
+	{typ: types.NewMap(types.Typ[types.String], types.Typ[types.Uint64])},
+	{typ: types.NewMap(types.NewNamed(types.NewTypeName(0, mockPkg, "Key", nil), types.Typ[types.String], nil), types.Typ[types.Int32])},
+	{typ: types.NewMap(types.Typ[types.Int], types.Typ[types.String])},
+	{typ: types.NewMap(types.Typ[types.Float64], types.NewSlice(types.Typ[types.Float64]))},
+	{typ: types.NewMap(types.Typ[types.Int32], types.NewStruct(nil, nil))},
+	{typ: types.NewMap(types.Typ[types.String], types.NewStruct(nil, nil))},
+	{typ: types.NewMap(types.Typ[types.Int], types.Typ[types.Bool])},
+
+	// Matrix types.
+	{typ: types.NewSlice(types.NewSlice(types.Typ[types.Float64]))},
+	{typ: types.NewSlice(types.NewSlice(types.Typ[types.Int32]))},
+	{typ: types.NewNamed(types.NewTypeName(0, mockBlas64, "General", nil), mockGeneral, nil)},
+	{typ: types.NewNamed(types.NewTypeName(0, mockBlas64, "GeneralCols", nil), mockGeneral, nil)},
+	{typ: types.NewNamed(types.NewTypeName(0, mockMat, "Dense", nil), types.NewStruct(nil, nil), nil)},
+
+	// Nullable types.
+	{typ: mockNullable("NullBool", "Bool", types.Typ[types.Bool])},
+	{typ: mockNullable("NullFloat64", "Float64", types.Typ[types.Float64])},
+	{typ: mockNullable("NullInt32", "Int32", types.Typ[types.Int32])},
+	{typ: mockNullable("NullString", "String", types.Typ[types.String])},
+
+	// Data frame types.
+	{
+		typ: types.NewSlice(types.NewNamed(types.NewTypeName(0, mockPkg, "Row", nil), types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.Typ[types.String], false),
+			types.NewField(0, mockPkg, "F2", types.Typ[types.Int], false),
+		}, []string{`rgo:"Rname"`}), nil)),
+	},
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "_", types.NewStruct(nil, nil), false),
+			types.NewField(0, mockPkg, "F1", types.NewSlice(types.Typ[types.String]), false),
+			types.NewField(0, mockPkg, "F2", types.NewSlice(types.Typ[types.Float64]), false),
+		}, []string{`rgo:",frame"`, `rgo:"Rname"`}),
+	},
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "_", types.NewStruct(nil, nil), false),
+			types.NewField(0, mockPkg, "X", types.NewSlice(types.Typ[types.Float64]), false),
+		}, []string{`rgo:",frame"`}),
+	},
+	{
+		// Structs of slices without the frame tag
+		// option are lists.
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "A", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(0, mockPkg, "B", types.NewSlice(types.Typ[types.String]), false),
+		}, nil),
+	},
+
+	// Time types.
+	{typ: mockTimeType},
+	{typ: types.NewSlice(mockTimeType)},
+	{typ: mockDuration},
+	{typ: types.NewSlice(mockDuration)},
+	{typ: mockDate, opts: pkg.Options{Date: "cloud.google.com/go/civil.Date"}},
+	{typ: types.NewSlice(mockDate), opts: pkg.Options{Date: "cloud.google.com/go/civil.Date"}},
+
+	// Enum types.
+	{typ: mockColor},
+	{typ: types.NewSlice(mockColor)},
+	{typ: mockShape},
+	{typ: types.NewSlice(mockShape)},
+
+	// Function types.
+	{typ: types.NewSignature(nil, types.NewTuple(types.NewVar(0, mockPkg, "x", types.Typ[types.Float64])), types.NewTuple(types.NewVar(0, mockPkg, "", types.Typ[types.Float64])), false)},
+	{typ: types.NewSignature(nil, types.NewTuple(types.NewVar(0, mockPkg, "x", types.NewSlice(types.Typ[types.Float64]))), types.NewTuple(types.NewVar(0, mockPkg, "", types.Typ[types.Float64]), types.NewVar(0, mockPkg, "", types.Universe.Lookup("error").Type())), false)},
+	{typ: types.NewSignature(nil, types.NewTuple(types.NewVar(0, mockPkg, "", types.Typ[types.Int32]), types.NewVar(0, mockPkg, "", types.Typ[types.String])), nil, false)},
+	{typ: types.NewSignature(nil, nil, types.NewTuple(types.NewVar(0, mockPkg, "", types.Universe.Lookup("error").Type())), false)},
+
+	// Raw R value types.
+	{
+		typ: types.NewNamed(types.NewTypeName(0, mockSEXP, "Value", nil), types.NewStruct([]*types.Var{
+			types.NewField(0, mockSEXP, "sexp", types.Typ[types.UnsafePointer], false),
+		}, nil), nil),
+	},
+
+	// Handle types.
+	{
+		typ: types.NewPointer(types.NewNamed(types.NewTypeName(0, mockPkg, "Handle", nil), types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "f", types.Typ[types.Int], false),
+		}, nil), nil)),
+	},
 
 	// Struct types.
 	{
@@ -133,22 +291,67 @@ var sexpFuncGoTests = []struct {
 			types.NewField(0, mockPkg, "F2", types.Typ[types.Bool], false),
 		}, []string{`rgo:"Rname"`}),
 	},
+
+	// Struct tag options.
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.Typ[types.String], false),
+			types.NewField(0, mockPkg, "F2", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(0, mockPkg, "F3", types.Typ[types.Int], false),
+			types.NewField(0, mockPkg, "F4", types.NewStruct([]*types.Var{
+				types.NewField(0, mockPkg, "F5", types.Typ[types.Bool], false),
+			}, nil), false),
+		}, []string{`rgo:"name,omitempty"`, `rgo:",omitempty"`, `rgo:"f3"`, `rgo:",inline"`}),
+	},
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.Typ[types.String], false),
+			types.NewField(0, mockPkg, "F2", types.NewPointer(types.NewStruct([]*types.Var{
+				types.NewField(0, mockPkg, "F3", types.Typ[types.Int], false),
+				types.NewField(0, mockPkg, "F4", types.Typ[types.Bool], false),
+			}, nil)), false),
+		}, []string{"", `rgo:",inline"`}),
+	},
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.Typ[types.String], false),
+			types.NewField(0, mockPkg, "F2", types.Typ[types.Float64], false),
+			types.NewField(0, mockPkg, "F3", types.Typ[types.Int], false),
+		}, []string{`json:"name"`, `json:"-" rgo:"value"`, `json:"f3,omitempty"`}),
+		opts: pkg.Options{JSONTags: true},
+	},
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(0, mockPkg, "F2", types.Typ[types.String], false),
+			types.NewField(0, mockPkg, "F3", types.Typ[types.Int], false),
+		}, []string{`rgo:"values"`, `rgo:"units,attr"`, `rgo:"iter,attr,omitempty"`}),
+	},
+
+	// S3 classes.
+	{
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(0, mockPkg, "F1", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(0, mockPkg, "F2", types.Typ[types.Int], false),
+		}, nil),
+		opts: pkg.Options{Classes: true},
+	},
 }
 
 func TestUnpackSEXPFuncGo(t *testing.T) {
-	if got := strings.TrimSpace(packSEXPFuncGo(nil)); got != "" {
+	if got := strings.TrimSpace(packSEXPFuncGo(pkg.Options{}, nil)); got != "" {
 		t.Errorf("unexpected output for empty slice: %s", got)
 	}
 	for i, test := range sexpFuncGoTests {
-		typs := []types.Type{
-			test.typ,
-			types.NewNamed(types.NewTypeName(0, mockPkg, "T", nil), test.typ, nil),
+		typs := []types.Type{test.typ}
+		if _, ok := test.typ.(*types.Named); !ok {
+			typs = append(typs, types.NewNamed(types.NewTypeName(0, mockPkg, "T", nil), test.typ, nil))
 		}
 		for _, typ := range typs {
-			got := []byte(strings.TrimSpace(unpackSEXPFuncGo([]types.Type{typ})))
+			got := []byte(strings.TrimSpace(unpackSEXPFuncGo(test.opts, []types.Type{typ})))
 
 			var named string
-			if _, ok := typ.(*types.Named); ok {
+			if typ != test.typ {
 				named = "-named"
 			}
 			golden := filepath.Join("testdata", fmt.Sprintf("unpackSEXP%s%s.golden", pkg.Mangle(test.typ), named))
@@ -178,19 +381,23 @@ func TestUnpackSEXPFuncGo(t *testing.T) {
 }
 
 func TestPackSEXPFuncGo(t *testing.T) {
-	if got := strings.TrimSpace(packSEXPFuncGo(nil)); got != "" {
+	if got := strings.TrimSpace(packSEXPFuncGo(pkg.Options{}, nil)); got != "" {
 		t.Errorf("unexpected output for empty slice: %s", got)
 	}
 	for i, test := range sexpFuncGoTests {
-		typs := []types.Type{
-			test.typ,
-			types.NewNamed(types.NewTypeName(0, mockPkg, "T", nil), test.typ, nil),
+		if _, ok := test.typ.(*types.Signature); ok {
+			// Functions are only passed from R to Go.
+			continue
+		}
+		typs := []types.Type{test.typ}
+		if _, ok := test.typ.(*types.Named); !ok {
+			typs = append(typs, types.NewNamed(types.NewTypeName(0, mockPkg, "T", nil), test.typ, nil))
 		}
 		for _, typ := range typs {
-			got := []byte(strings.TrimSpace(packSEXPFuncGo([]types.Type{typ})))
+			got := []byte(strings.TrimSpace(packSEXPFuncGo(test.opts, []types.Type{typ})))
 
 			var named string
-			if _, ok := typ.(*types.Named); ok {
+			if typ != test.typ {
 				named = "-named"
 			}
 			golden := filepath.Join("testdata", fmt.Sprintf("packSEXP%s%s.golden", pkg.Mangle(test.typ), named))
@@ -218,3 +425,205 @@ func TestPackSEXPFuncGo(t *testing.T) {
 		}
 	}
 }
+
+func TestInt64Helpers(t *testing.T) {
+	for _, mode := range []pkg.Int64Mode{pkg.Integer64, pkg.Double, pkg.Character} {
+		for _, sentinel := range []bool{false, true} {
+			got := []byte(strings.TrimSpace(int64Helpers(pkg.Options{Int64: mode, NASentinel: sentinel})))
+
+			name := fmt.Sprintf("int64Helpers-%s", mode)
+			if sentinel {
+				name += "-sentinel"
+			}
+			golden := filepath.Join("testdata", name+".golden")
+			if *regenerate {
+				err := ioutil.WriteFile(golden, got, 0o664)
+				if err != nil {
+					t.Fatalf("failed to write golden data: %v", err)
+				}
+				continue
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden data: %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				var buf bytes.Buffer
+				err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				t.Errorf("unexpected generated code for %s int64 representation (sentinel=%t):\n%s", mode, sentinel, &buf)
+			}
+		}
+	}
+}
+
+func TestTimeHelpers(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		info *pkg.Info
+	}{
+		{name: "time", info: &pkg.Info{}},
+		{
+			name: "date",
+			info: &pkg.Info{
+				Packers: map[string]types.Type{pkg.Mangle(mockDate): mockDate},
+				Options: pkg.Options{Date: "cloud.google.com/go/civil.Date", NASentinel: true},
+			},
+		},
+	} {
+		got := []byte(strings.TrimSpace(timeHelpers(test.info)))
+
+		golden := filepath.Join("testdata", fmt.Sprintf("timeHelpers-%s.golden", test.name))
+		if *regenerate {
+			err := ioutil.WriteFile(golden, got, 0o664)
+			if err != nil {
+				t.Fatalf("failed to write golden data: %v", err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden data: %v", err)
+		}
+
+		if !bytes.Equal(got, want) {
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected generated code for %s helpers:\n%s", test.name, &buf)
+		}
+	}
+}
+
+func TestFactorHelpers(t *testing.T) {
+	got := []byte(strings.TrimSpace(factorHelpers([]types.Type{mockColor, mockShape})))
+
+	golden := filepath.Join("testdata", "factorHelpers.golden")
+	if *regenerate {
+		err := ioutil.WriteFile(golden, got, 0o664)
+		if err != nil {
+			t.Fatalf("failed to write golden data: %v", err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden data: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		var buf bytes.Buffer
+		err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		t.Errorf("unexpected generated code for factor helpers:\n%s", &buf)
+	}
+}
+
+func TestBigHelpers(t *testing.T) {
+	for _, gmp := range []bool{false, true} {
+		got := []byte(strings.TrimSpace(bigHelpers(pkg.Options{GMP: gmp})))
+
+		name := "bigHelpers"
+		if gmp {
+			name += "-gmp"
+		}
+		golden := filepath.Join("testdata", name+".golden")
+		if *regenerate {
+			err := ioutil.WriteFile(golden, got, 0o664)
+			if err != nil {
+				t.Fatalf("failed to write golden data: %v", err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden data: %v", err)
+		}
+
+		if !bytes.Equal(got, want) {
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected generated code for math/big helpers (gmp=%t):\n%s", gmp, &buf)
+		}
+	}
+}
+
+func TestImageHelpers(t *testing.T) {
+	for _, native := range []bool{false, true} {
+		got := []byte(strings.TrimSpace(imageHelpers(pkg.Options{NativeRaster: native})))
+
+		name := "imageHelpers"
+		if native {
+			name += "-nativeRaster"
+		}
+		golden := filepath.Join("testdata", name+".golden")
+		if *regenerate {
+			err := ioutil.WriteFile(golden, got, 0o664)
+			if err != nil {
+				t.Fatalf("failed to write golden data: %v", err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden data: %v", err)
+		}
+
+		if !bytes.Equal(got, want) {
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected generated code for image helpers (nativeRaster=%t):\n%s", native, &buf)
+		}
+	}
+}
+
+func TestDynamicHelpers(t *testing.T) {
+	for _, mode := range []pkg.Int64Mode{"", pkg.Double, pkg.Integer64} {
+		got := []byte(strings.TrimSpace(dynamicHelpers(pkg.Options{Int64: mode})))
+
+		name := "dynamicHelpers"
+		if mode != "" {
+			name += "-" + string(mode)
+		}
+		golden := filepath.Join("testdata", name+".golden")
+		if *regenerate {
+			err := ioutil.WriteFile(golden, got, 0o664)
+			if err != nil {
+				t.Fatalf("failed to write golden data: %v", err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden data: %v", err)
+		}
+
+		if !bytes.Equal(got, want) {
+			var buf bytes.Buffer
+			err := diff.Text("got", "want", got, want, &buf, write.TerminalColor())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			t.Errorf("unexpected generated code for dynamic helpers (Int64=%q):\n%s", mode, &buf)
+		}
+	}
+}
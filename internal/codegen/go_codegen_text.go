@@ -0,0 +1,80 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// textOf returns how typ, or the elements of typ if it is a slice, are
+// exchanged using their text form, and whether typ is a slice.
+func textOf(opts pkg.Options, typ types.Type) (kind pkg.TextKind, slice bool) {
+	if s, ok := typ.(*types.Slice); ok {
+		return opts.Text(s.Elem()), true
+	}
+	return opts.Text(typ), false
+}
+
+func packText(buf *bytes.Buffer, kind pkg.TextKind, slice bool) {
+	str := types.Typ[types.String]
+	if !slice {
+		if kind == pkg.Stringer {
+			fmt.Fprintf(buf, "\treturn packSEXP%s(p.String())\n", pkg.Mangle(str))
+			return
+		}
+		fmt.Fprintf(buf, `	b, err := p.MarshalText()
+	if err != nil {
+		panic(err)
+	}
+	return packSEXP%s(string(b))
+`, pkg.Mangle(str))
+		return
+	}
+	fmt.Fprintln(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	s := make([]string, len(p))
+	for i := range p {`)
+	if kind == pkg.Stringer {
+		fmt.Fprintln(buf, "\t\ts[i] = p[i].String()")
+	} else {
+		fmt.Fprintln(buf, `		b, err := p[i].MarshalText()
+		if err != nil {
+			panic(err)
+		}
+		s[i] = string(b)`)
+	}
+	fmt.Fprintf(buf, "\t}\n\treturn packSEXP%s(s)\n", pkg.Mangle(types.NewSlice(str)))
+}
+
+func unpackText(buf *bytes.Buffer, typ types.Type, slice bool) {
+	if !slice {
+		fmt.Fprintf(buf, `	var r %s
+	err := r.UnmarshalText([]byte(unpackSEXP%s(p)))
+	if err != nil {
+		panic(err)
+	}
+	return r
+`, nameOf(typ), pkg.Mangle(types.Typ[types.String]))
+		return
+	}
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	s := unpackSEXP%s(p)
+	r := make(%s, len(s))
+	for i, v := range s {
+		err := r[i].UnmarshalText([]byte(v))
+		if err != nil {
+			panic(err)
+		}
+	}
+	return r
+`, pkg.Mangle(types.NewSlice(types.Typ[types.String])), nameOf(typ))
+}
@@ -0,0 +1,274 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// temporalOf returns the time class of typ, or of the elements of typ if
+// it is a slice, and whether typ is a slice.
+func temporalOf(opts pkg.Options, typ types.Type) (kind pkg.TemporalKind, slice bool) {
+	if s, ok := typ.(*types.Slice); ok {
+		return opts.Temporal(s.Elem()), true
+	}
+	return opts.Temporal(typ), false
+}
+
+// temporalSuffix returns the name suffix of the time helper functions
+// for the given time class.
+func temporalSuffix(kind pkg.TemporalKind) string {
+	switch kind {
+	case pkg.Time:
+		return "Times"
+	case pkg.Duration:
+		return "Durations"
+	case pkg.Date:
+		return "Dates"
+	default:
+		panic(fmt.Sprintf("unhandled time class: %d", kind))
+	}
+}
+
+func packTemporal(buf *bytes.Buffer, typ types.Type, kind pkg.TemporalKind, slice bool) {
+	if !slice {
+		fmt.Fprintf(buf, "\treturn pack%s([]%s{p})\n", temporalSuffix(kind), nameOf(typ))
+		return
+	}
+	fmt.Fprintf(buf, `	if p == nil {
+		return C.R_NilValue
+	}
+	return pack%s(p)
+`, temporalSuffix(kind))
+}
+
+func unpackTemporal(buf *bytes.Buffer, kind pkg.TemporalKind, slice bool) {
+	if !slice {
+		fmt.Fprintf(buf, "\treturn unpack%s(p)[0]\n", temporalSuffix(kind))
+		return
+	}
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	return unpack%s(p)
+`, temporalSuffix(kind))
+}
+
+// timeHelpers returns the Go source for the functions converting between
+// time, duration and date slices and R POSIXct, difftime and Date vectors.
+func timeHelpers(info *pkg.Info) string {
+	var date string
+	if typ := info.DateType(); typ != nil {
+		date = nameOf(typ)
+	}
+	// Maximum length array type for this element type.
+	type a [1 << 46]float64
+	// Maximum length array type for this element type.
+	type b [1 << 47]int32
+	var buf strings.Builder
+	err := timeHelpersTmpl.Execute(&buf, struct {
+		Max        int
+		MaxInt     int
+		NASentinel bool
+		Date       string
+	}{Max: len(&a{}), MaxInt: len(&b{}), NASentinel: info.Options.NASentinel, Date: date})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+var timeHelpersTmpl = template.Must(template.New("time").Parse(`// realsOf returns the values of the R integer or double vector p as
+// float64 values. Integer NA values are returned as NaN.
+func realsOf(p C.SEXP) []float64 {
+	n := C.Rf_xlength(p)
+	if C.Rf_isInteger(p) == 0 {
+		return (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(p)))[:n]
+	}
+	r := make([]float64, n)
+	for i, v := range (*[{{.MaxInt}}]int32)(unsafe.Pointer(C.INTEGER(p)))[:n] {
+		if C.int(v) == C.R_NaInt {
+			r[i] = math.NaN()
+		} else {
+			r[i] = float64(v)
+		}
+	}
+	return r
+}
+
+// setClass sets the class attribute of p.
+func setClass(p C.SEXP, class ...string) {
+	cls := C.Rf_allocVector(C.STRSXP, C.R_xlen_t(len(class)))
+	C.Rf_protect(cls)
+	defer C.Rf_unprotect(1)
+	for i, c := range class {
+		C.SET_STRING_ELT(cls, C.R_xlen_t(i), C.Rf_mkCharLenCE(C._GoStringPtr(c), C.int(len(c)), C.CE_UTF8))
+	}
+	C.Rf_classgets(p, cls)
+}
+
+// setStringAttrib sets the named attribute of p to a character
+// vector holding val.
+func setStringAttrib(p C.SEXP, name, val string) {
+	sym := C.CString(name)
+	defer C.free(unsafe.Pointer(sym))
+	s := C.Rf_allocVector(C.STRSXP, 1)
+	C.Rf_protect(s)
+	defer C.Rf_unprotect(1)
+	C.SET_STRING_ELT(s, 0, C.Rf_mkCharLenCE(C._GoStringPtr(val), C.int(len(val)), C.CE_UTF8))
+	C.Rf_setAttrib(p, C.Rf_install(sym), s)
+}
+
+// stringAttrib returns the first element of the named character
+// attribute of p, or the empty string if p has no such attribute.
+func stringAttrib(p C.SEXP, name string) string {
+	sym := C.CString(name)
+	defer C.free(unsafe.Pointer(sym))
+	a := C.Rf_getAttrib(p, C.Rf_install(sym))
+	if C.Rf_isString(a) == 0 || C.Rf_xlength(a) == 0 {
+		return ""
+	}
+	return C.R_gostring(a, 0)
+}
+
+// packTimes returns an R POSIXct vector holding the values in p. Zero
+// times are packed as NA. The time zone of the vector is the location
+// of the first time that is not in the local time zone.
+func packTimes(p []time.Time) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	var tz string
+	for i, t := range p {
+		if t.IsZero() {
+			s[i] = float64(C.R_NaReal)
+			continue
+		}
+		if loc := t.Location(); tz == "" && loc != time.Local {
+			tz = loc.String()
+		}
+		s[i] = float64(t.Unix()) + float64(t.Nanosecond())/1e9
+	}
+	setClass(r, "POSIXct", "POSIXt")
+	setStringAttrib(r, "tzone", tz)
+	return r
+}
+
+// unpackTimes returns the values in the R POSIXct vector p in the
+// time zone of the vector. NA values are returned as the zero time.
+func unpackTimes(p C.SEXP) []time.Time {
+	loc := time.Local
+	if tz := stringAttrib(p, "tzone"); tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			panic(err)
+		}
+	}
+	v := realsOf(p)
+	r := make([]time.Time, len(v))
+	for i, sec := range v {
+		if math.IsNaN(sec) {
+			continue
+		}
+		whole, frac := math.Modf(sec)
+		r[i] = time.Unix(int64(whole), int64(math.Round(frac*1e9))).In(loc)
+	}
+	return r
+}
+
+// packDurations returns an R difftime vector in seconds holding the
+// values in p.
+func packDurations(p []time.Duration) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	for i, d := range p {
+		s[i] = d.Seconds()
+	}
+	setClass(r, "difftime")
+	setStringAttrib(r, "units", "secs")
+	return r
+}
+
+// difftimeUnits holds the lengths of R difftime units.
+var difftimeUnits = map[string]time.Duration{
+	"secs":  time.Second,
+	"mins":  time.Minute,
+	"hours": time.Hour,
+	"days":  24 * time.Hour,
+	"weeks": 7 * 24 * time.Hour,
+}
+
+// unpackDurations returns the values in the R difftime vector p.
+func unpackDurations(p C.SEXP) []time.Duration {
+	units := stringAttrib(p, "units")
+	unit, ok := difftimeUnits[units]
+	if !ok {
+		panic(fmt.Sprintf("unknown difftime units: %q", units))
+	}
+	v := realsOf(p)
+	r := make([]time.Duration, len(v))
+	for i, d := range v {
+		if math.IsNaN(d) {
+{{- if .NASentinel}}
+			r[i] = math.MinInt64
+			continue
+{{- else}}
+			panic(fmt.Sprintf("NA not allowed for Go time.Duration value at index %d", i+1))
+{{- end}}
+		}
+		r[i] = time.Duration(math.Round(d * float64(unit)))
+	}
+	return r
+}
+{{- with .Date}}
+
+// secondsPerDay is the number of seconds in an R Date day.
+const secondsPerDay = 24 * 60 * 60
+
+// packDates returns an R Date vector holding the values in p. Zero
+// dates are packed as NA.
+func packDates(p []{{.}}) C.SEXP {
+	r := C.Rf_allocVector(C.REALSXP, C.R_xlen_t(len(p)))
+	C.Rf_protect(r)
+	defer C.Rf_unprotect(1)
+	s := (*[{{$.Max}}]float64)(unsafe.Pointer(C.REAL(r)))[:len(p)]
+	for i, d := range p {
+		if d.Year == 0 && d.Month == 0 && d.Day == 0 {
+			s[i] = float64(C.R_NaReal)
+			continue
+		}
+		s[i] = float64(time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay)
+	}
+	setClass(r, "Date")
+	return r
+}
+
+// unpackDates returns the values in the R Date vector p. NA values
+// are returned as the zero date.
+func unpackDates(p C.SEXP) []{{.}} {
+	v := realsOf(p)
+	r := make([]{{.}}, len(v))
+	for i, days := range v {
+		if math.IsNaN(days) {
+			continue
+		}
+		t := time.Unix(int64(math.Floor(days))*secondsPerDay, 0).UTC()
+		r[i] = {{.}}{Year: t.Year(), Month: t.Month(), Day: t.Day()}
+	}
+	return r
+}
+{{- end}}
+
+`))
@@ -0,0 +1,83 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+
+	"github.com/rgonomic/rgo/internal/pkg"
+)
+
+// variantClass returns the S3 class identifying R values of the sealed
+// interface implementation typ. This is the class of the implementation
+// if it has one, and otherwise the qualified name of the type, or of the
+// type pointed to if typ is a pointer type.
+func variantClass(opts pkg.Options, typ types.Type) string {
+	if class := classOf(opts, typ); class != "" {
+		return class
+	}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	return nameOf(typ)
+}
+
+func packUnion(buf *bytes.Buffer, typ types.Type, impls []types.Type, opts pkg.Options) {
+	fmt.Fprintln(buf, "\tswitch p := p.(type) {\n\tcase nil:\n\t\treturn C.R_NilValue")
+	for _, impl := range impls {
+		fmt.Fprintf(buf, "\tcase %s:\n", nameOf(impl))
+		if classOf(opts, impl) != "" {
+			// The implementation adds its own class.
+			fmt.Fprintf(buf, "\t\treturn packSEXP%s(p)\n", pkg.Mangle(impl))
+			continue
+		}
+		fmt.Fprintf(buf, `		r := packSEXP%s(p)
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+`, pkg.Mangle(impl))
+		_, _, nilable := rTypeOf(opts, impl)
+		if nilable {
+			// Attributes cannot be set on NULL.
+			fmt.Fprintln(buf, "\t\tif C.Rf_isNull(r) != 0 {\n\t\t\treturn r\n\t\t}")
+		}
+		fmt.Fprintf(buf, `		cls := C.CString(%q)
+		defer C.free(unsafe.Pointer(cls))
+		C.R_addClass(r, cls)
+		return r
+`, variantClass(opts, impl))
+	}
+	for _, impl := range impls {
+		if _, ok := impl.(*types.Pointer); ok {
+			continue
+		}
+		// Pointers to value implementations also
+		// implement the interface.
+		fmt.Fprintf(buf, `	case *%s:
+		if p == nil {
+			return C.R_NilValue
+		}
+		return packSEXP%s(*p)
+`, nameOf(impl), pkg.Mangle(typ))
+	}
+	fmt.Fprintf(buf, `	default:
+		panic(fmt.Sprintf("unhandled Go %s implementation: %%T", p))
+	}
+`, nameOf(typ))
+}
+
+func unpackUnion(buf *bytes.Buffer, typ types.Type, impls []types.Type, opts pkg.Options) {
+	fmt.Fprintln(buf, "\tif C.Rf_isNull(p) != 0 {\n\t\treturn nil\n\t}")
+	for i, impl := range impls {
+		fmt.Fprintf(buf, `	cls%[1]d := C.CString(%[2]q)
+	defer C.free(unsafe.Pointer(cls%[1]d))
+	if C.Rf_inherits(p, cls%[1]d) != 0 {
+		return unpackSEXP%[3]s(p)
+	}
+`, i, variantClass(opts, impl), pkg.Mangle(impl))
+	}
+	fmt.Fprintf(buf, "\tpanic(%q)\n", fmt.Sprintf("missing class for Go %s value", nameOf(typ)))
+}
@@ -8,17 +8,18 @@ import (
 	"bytes"
 	"fmt"
 	"go/types"
+	"strings"
 
 	"github.com/rgonomic/rgo/internal/pkg"
 )
 
 // unpackSEXPFuncGo returns the source of functions to unpack R SEXP parameters
 // into the given Go types.
-func unpackSEXPFuncGo(typs []types.Type) string {
+func unpackSEXPFuncGo(opts pkg.Options, typs []types.Type) string {
 	var buf bytes.Buffer
 	for _, typ := range typs {
 		fmt.Fprintf(&buf, "func unpackSEXP%s(p C.SEXP) %s {\n", pkg.Mangle(typ), nameOf(typ))
-		unpackSEXPFuncBodyGo(&buf, typ)
+		unpackSEXPFuncBodyGo(&buf, typ, opts)
 		buf.WriteString("}\n\n")
 	}
 	return buf.String()
@@ -26,37 +27,108 @@ func unpackSEXPFuncGo(typs []types.Type) string {
 
 // unpackSEXPFuncBodyGo returns the body of a function to unpack R SEXP parameters
 // into the given Go types.
-func unpackSEXPFuncBodyGo(buf *bytes.Buffer, typ types.Type) {
+func unpackSEXPFuncBodyGo(buf *bytes.Buffer, typ types.Type, opts pkg.Options) {
+	if kind := pkg.Matrix(typ); kind != pkg.NotMatrix {
+		unpackMatrix(buf, typ, kind)
+		return
+	}
+	if kind := opts.Frame(typ); kind != pkg.NotFrame {
+		unpackFrame(buf, typ, kind, opts)
+		return
+	}
+	if kind, slice := temporalOf(opts, typ); kind != pkg.NotTemporal {
+		unpackTemporal(buf, kind, slice)
+		return
+	}
+	if kind, ptr, slice := pkg.BigOf(typ); kind != pkg.NotBig {
+		unpackBig(buf, kind, ptr, slice)
+		return
+	}
+	if kind := pkg.Image(typ); kind != pkg.NotImage {
+		unpackImage(buf, typ, kind)
+		return
+	}
+	if pkg.Arrow(typ) != pkg.NotArrow {
+		unpackArrow(buf, typ)
+		return
+	}
+	if pkg.IsSEXP(typ) {
+		fmt.Fprintln(buf, "\treturn sexp.New(unsafe.Pointer(p))")
+		return
+	}
+	if kind, slice := textOf(opts, typ); kind != pkg.NotText {
+		unpackText(buf, typ, slice)
+		return
+	}
+	if enum, slice := enumOf(typ); enum != nil {
+		unpackEnum(buf, enum, slice)
+		return
+	}
+	if elem := pkg.Nullable(typ); elem != nil {
+		unpackNullable(buf, typ.(*types.Named), elem)
+		return
+	}
+	if pkg.IsDynamic(typ) {
+		unpackDynamic(buf, typ)
+		return
+	}
 	switch typ := typ.(type) {
 	case *types.Named:
-		unpackNamed(buf, typ)
+		unpackNamed(buf, typ, opts)
 
 	case *types.Array:
 		unpackArray(buf, typ)
 
 	case *types.Basic:
-		unpackBasic(buf, typ)
+		unpackBasic(buf, typ, opts)
 
 	case *types.Map:
-		unpackMap(buf, typ)
+		unpackMap(buf, typ, opts)
 
 	case *types.Pointer:
-		unpackPointer(buf, typ)
+		unpackPointer(buf, typ, opts)
+
+	case *types.Signature:
+		unpackFunc(buf, typ)
 
 	case *types.Slice:
-		unpackSlice(buf, typ)
+		unpackSlice(buf, typ, opts)
 
 	case *types.Struct:
-		unpackStruct(buf, typ)
+		unpackStruct(buf, typ, typ, opts)
 
 	default:
 		panic(fmt.Sprintf("unhandled type: %s", typ))
 	}
 }
 
-func unpackNamed(buf *bytes.Buffer, typ *types.Named) {
+func unpackNamed(buf *bytes.Buffer, typ *types.Named, opts pkg.Options) {
+	if impls := pkg.Union(typ); impls != nil {
+		unpackUnion(buf, typ, impls, opts)
+		return
+	}
+	if class := opts.Class(typ); class != "" {
+		var null string
+		if _, _, nilable := rTypeOf(opts, typ); nilable {
+			null = "C.Rf_isNull(p) == 0 && "
+		}
+		fmt.Fprintf(buf, `	cls := C.CString(%[1]q)
+	defer C.free(unsafe.Pointer(cls))
+	if %[2]sC.Rf_inherits(p, cls) == 0 {
+		panic(%[3]q)
+	}
+`, class, null, fmt.Sprintf("missing class %q for Go %s value", class, nameOf(typ)))
+	}
 	switch under := typ.Underlying().(type) {
-	case *types.Array, *types.Map, *types.Pointer, *types.Slice, *types.Struct:
+	case *types.Struct:
+		if opts.SkipsFields(under) {
+			// The unnamed struct type cannot be written
+			// without the skipped fields.
+			unpackStruct(buf, typ, under, opts)
+			return
+		}
+		fmt.Fprintf(buf, "\treturn unpackSEXP%s(p)\n", pkg.Mangle(under))
+	case *types.Array, *types.Map, *types.Pointer, *types.Slice:
 		fmt.Fprintf(buf, "\treturn unpackSEXP%s(p)\n", pkg.Mangle(under))
 	default:
 		fmt.Fprintf(buf, "\treturn %s(unpackSEXP%s(p))\n", nameOf(typ), pkg.Mangle(under))
@@ -64,6 +136,10 @@ func unpackNamed(buf *bytes.Buffer, typ *types.Named) {
 }
 
 func unpackArray(buf *bytes.Buffer, typ *types.Array) {
+	if dims, elem := pkg.ArrayDims(typ); dims != nil {
+		unpackNestedArray(buf, typ, dims, elem)
+		return
+	}
 	// TODO(kortschak): Only do this for [n]int32, [n]float64, [n]complex128 and [n]byte.
 	// Otherwise we have a double copy.
 	fmt.Fprintf(buf, `	var a %s
@@ -72,22 +148,24 @@ func unpackArray(buf *bytes.Buffer, typ *types.Array) {
 `, typ, pkg.Mangle(types.NewSlice(typ.Elem())))
 }
 
-func unpackBasic(buf *bytes.Buffer, typ *types.Basic) {
+func unpackBasic(buf *bytes.Buffer, typ *types.Basic, opts pkg.Options) {
 	switch typ.Kind() {
 	case types.Bool:
-		fmt.Fprintln(buf, "\treturn *C.RAW(p) == 1")
-	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64, types.Uint, types.Uint16, types.Uint32, types.Uint64:
-		fmt.Fprintf(buf, "\treturn %s(*C.INTEGER(p))\n", nameOf(typ))
+		fmt.Fprintf(buf, "\tv := *C.LOGICAL(p)\n%s\treturn v != 0\n", naCheck(opts, typ, "v", "", "\t"))
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Uint, types.Uint16, types.Uint32:
+		fmt.Fprintf(buf, "\tv := *C.INTEGER(p)\n%s\treturn %s(v)\n", naCheck(opts, typ, "v", "", "\t"), nameOf(typ))
+	case types.Int64, types.Uint64:
+		fmt.Fprintf(buf, "\treturn unpack%s(p)[0]\n", int64Suffix(typ))
 	case types.Uint8:
 		fmt.Fprintf(buf, "\treturn %s(*C.RAW(p))\n", nameOf(typ))
 	case types.Float64, types.Float32:
-		fmt.Fprintf(buf, "\treturn %s(*C.REAL(p))\n", nameOf(typ))
+		fmt.Fprintf(buf, "\tv := *C.REAL(p)\n%s\treturn %s(v)\n", naCheck(opts, typ, "v", "", "\t"), nameOf(typ))
 	case types.Complex128:
-		fmt.Fprintf(buf, "\treturn %s(*(*complex128)(unsafe.Pointer(C.COMPLEX(p))))\n", nameOf(typ))
+		fmt.Fprintf(buf, "\tv := *(*complex128)(unsafe.Pointer(C.COMPLEX(p)))\n%s\treturn %s(v)\n", naCheck(opts, typ, "v", "", "\t"), nameOf(typ))
 	case types.Complex64:
 		fmt.Fprintf(buf, "\treturn %s(unpackSEXP%s(p))\n", nameOf(typ), pkg.Mangle(types.Typ[types.Complex128]))
 	case types.String:
-		fmt.Fprintln(buf, "\treturn C.R_gostring(p, 0)")
+		fmt.Fprintf(buf, "%s\treturn C.R_gostring(p, 0)\n", naCheck(opts, typ, "C.STRING_ELT(p, 0)", "", "\t"))
 	case types.UnsafePointer:
 		fmt.Fprintln(buf, "\treturn unsafe.Pointer(p)")
 	default:
@@ -95,11 +173,30 @@ func unpackBasic(buf *bytes.Buffer, typ *types.Basic) {
 	}
 }
 
-func unpackMap(buf *bytes.Buffer, typ *types.Map) {
+func unpackMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
 	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
 		return nil
 	}
 `)
+	switch pkg.Map(typ) {
+	case pkg.KeyedMap:
+		unpackKeyedMap(buf, typ, opts)
+		return
+	case pkg.SetMap:
+		unpackSetMap(buf, typ, opts)
+		return
+	}
+	if !types.Identical(typ.Key(), types.Typ[types.String]) {
+		m := types.NewMap(types.Typ[types.String], typ.Elem())
+		fmt.Fprintf(buf, `	m := unpackSEXP%s(p)
+	r := make(%s, len(m))
+	for k, v := range m {
+		r[%s(k)] = v
+	}
+	return r
+`, pkg.Mangle(m), nameOf(typ), nameOf(typ.Key()))
+		return
+	}
 	elem := typ.Elem()
 	if basic, ok := elem.Underlying().(*types.Basic); ok {
 		switch basic.Kind() {
@@ -116,11 +213,25 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 	}
 	values := (*[%[1]d]int32)(unsafe.Pointer(C.INTEGER(p)))[:n:n]
 	for i, elem := range values {
+%[3]s		key := string(C.R_gostring(names, C.R_xlen_t(i)))
+		r[key] = %[2]s(elem)
+	}
+	return r
+`, len(&a{}), nameOf(elem), naCheck(opts, basic, "elem", "i", "\t\t"))
+			return
+		case types.Int64, types.Uint64:
+			fmt.Fprintf(buf, `	n := int(C.Rf_xlength(p))
+	r := make(map[string]%[2]s, n)
+	names := C.getAttrib(p, C.R_NamesSymbol)
+	if names == C.R_NilValue {
+		panic("no names attribute for map keys")
+	}
+	for i, elem := range unpack%[1]s(p) {
 		key := string(C.R_gostring(names, C.R_xlen_t(i)))
 		r[key] = %[2]s(elem)
 	}
 	return r
-`, len(&a{}), nameOf(elem))
+`, int64Suffix(basic), nameOf(elem))
 			return
 		case types.Uint8:
 			// Maximum length array type for this element type.
@@ -150,11 +261,11 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 	}
 	values := (*[%[1]d]float64)(unsafe.Pointer(C.REAL(p)))[:n:n]
 	for i, elem := range values {
-		key := string(C.R_gostring(names, C.R_xlen_t(i)))
+%[3]s		key := string(C.R_gostring(names, C.R_xlen_t(i)))
 		r[key] = %[2]s(elem)
 	}
 	return r
-`, len(&a{}), nameOf(elem))
+`, len(&a{}), nameOf(elem), naCheck(opts, basic, "elem", "i", "\t\t"))
 			return
 		case types.Complex64, types.Complex128:
 			// Maximum length array type for this element type.
@@ -167,11 +278,11 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 	}
 	values := (*[%[1]d]complex128)(unsafe.Pointer(C.COMPLEX(p)))[:n:n]
 	for i, elem := range values {
-		key := string(C.R_gostring(names, C.R_xlen_t(i)))
+%[3]s		key := string(C.R_gostring(names, C.R_xlen_t(i)))
 		r[key] = %[2]s(elem)
 	}
 	return r
-`, len(&a{}), nameOf(elem))
+`, len(&a{}), nameOf(elem), naCheck(opts, basic, "elem", "i", "\t\t"))
 			return
 		case types.Bool:
 			// Maximum length array type for this element type.
@@ -184,11 +295,11 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 	}
 	values := (*[%[1]d]int32)(unsafe.Pointer(C.LOGICAL(p)))[:n:n]
 	for i, elem := range values {
-		key := string(C.R_gostring(names, C.R_xlen_t(i)))
+%[3]s		key := string(C.R_gostring(names, C.R_xlen_t(i)))
 		r[key] = (elem == 1)
 	}
 	return r
-`, len(&a{}), nameOf(elem))
+`, len(&a{}), nameOf(elem), naCheck(opts, basic, "elem", "i", "\t\t"))
 			return
 		case types.String:
 			fmt.Fprintf(buf, `	n := int(C.Rf_xlength(p))
@@ -198,11 +309,11 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 		panic("no names attribute for map keys")
 	}
 	for i := 0; i < n; i++ {
-		key := string(C.R_gostring(names, C.R_xlen_t(i)))
+%[2]s		key := string(C.R_gostring(names, C.R_xlen_t(i)))
 		r[key] = %[1]s(C.R_gostring(p, C.R_xlen_t(i)))
 	}
 	return r
-`, nameOf(elem))
+`, nameOf(elem), naCheck(opts, basic, "C.STRING_ELT(p, C.R_xlen_t(i))", "i", "\t\t"))
 			return
 		}
 	}
@@ -221,7 +332,156 @@ func unpackMap(buf *bytes.Buffer, typ *types.Map) {
 `, nameOf(elem), pkg.Mangle(elem))
 }
 
-func unpackPointer(buf *bytes.Buffer, typ *types.Pointer) {
+func unpackKeyedMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
+	keys := opts.MapSlice(typ.Key())
+	values := opts.MapSlice(typ.Elem())
+	key := "k"
+	var keyDecl string
+	if !types.Identical(typ.Key(), keys.Elem()) {
+		key = "key"
+		keyDecl = fmt.Sprintf("\t\tkey := %s\n", convert(typ.Key(), keys.Elem(), "k"))
+	}
+	fmt.Fprintf(buf, `	key_keys := C.CString("keys")
+	defer C.free(unsafe.Pointer(key_keys))
+	i := C.getListElementIndex(p, key_keys)
+	if i < 0 {
+		panic("no list element name for map keys")
+	}
+	keys := unpackSEXP%[2]s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+	key_values := C.CString("values")
+	defer C.free(unsafe.Pointer(key_values))
+	i = C.getListElementIndex(p, key_values)
+	if i < 0 {
+		panic("no list element name for map values")
+	}
+	values := unpackSEXP%[3]s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+	if len(keys) != len(values) {
+		panic(fmt.Sprintf("map keys has length %%d, values has length %%d", len(keys), len(values)))
+	}
+	r := make(%[1]s, len(keys))
+	for i, k := range keys {
+%[4]s		if _, ok := r[%[5]s]; ok {
+			panic(fmt.Sprintf("duplicate map key at index %%d", i+1))
+		}
+		r[%[5]s] = %[6]s
+	}
+	return r
+`, nameOf(typ), pkg.Mangle(keys), pkg.Mangle(values), keyDecl, key, convert(typ.Elem(), values.Elem(), "values[i]"))
+}
+
+func unpackSetMap(buf *bytes.Buffer, typ *types.Map, opts pkg.Options) {
+	keys := opts.MapSlice(typ.Key())
+	member := nameOf(typ.Elem()) + "{}"
+	if _, ok := typ.Elem().Underlying().(*types.Basic); ok {
+		member = "true"
+	}
+	fmt.Fprintf(buf, `	keys := unpackSEXP%s(p)
+	r := make(%s, len(keys))
+	for _, k := range keys {
+		r[%s] = %s
+	}
+	return r
+`, pkg.Mangle(keys), nameOf(typ), convert(typ.Key(), keys.Elem(), "k"), member)
+}
+
+func unpackFunc(buf *bytes.Buffer, typ *types.Signature) {
+	par := typ.Params()
+	res := typ.Results()
+	var (
+		params  []string
+		results []string
+		value   types.Type
+		hasErr  bool
+	)
+	for i := 0; i < par.Len(); i++ {
+		params = append(params, fmt.Sprintf("a%d %s", i, nameOf(par.At(i).Type())))
+	}
+	for i := 0; i < res.Len(); i++ {
+		t := res.At(i).Type()
+		results = append(results, nameOf(t))
+		if pkg.IsError(t) {
+			hasErr = true
+		} else {
+			value = t
+		}
+	}
+	sig := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		sig += " " + results[0]
+	default:
+		sig += fmt.Sprintf(" (%s)", strings.Join(results, ", "))
+	}
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	if C.Rf_isFunction(p) == 0 {
+		panic("argument is not a function")
+	}
+	return %s {
+		args := C.Rf_allocVector(C.VECSXP, %d)
+		C.Rf_protect(args)
+		defer C.Rf_unprotect(1)
+`, sig, par.Len())
+	for i := 0; i < par.Len(); i++ {
+		fmt.Fprintf(buf, "\t\tC.SET_VECTOR_ELT(args, %[1]d, packSEXP%[2]s(a%[1]d))\n", i, pkg.Mangle(par.At(i).Type()))
+	}
+	fmt.Fprintln(buf, "\t\tr, err := callR(p, args)")
+	switch {
+	case value == nil && !hasErr:
+		fmt.Fprint(buf, `		if err != nil {
+			panic(err)
+		}
+	}
+`)
+	case value == nil && hasErr:
+		fmt.Fprint(buf, `		return err
+	}
+`)
+	case !hasErr:
+		fmt.Fprintf(buf, `		if err != nil {
+			panic(err)
+		}
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		return unpackSEXP%s(r)
+	}
+`, pkg.Mangle(value))
+	default:
+		fmt.Fprintf(buf, `		if err != nil {
+			var zero %s
+			return zero, err
+		}
+		C.Rf_protect(r)
+		defer C.Rf_unprotect(1)
+		return unpackSEXP%s(r), nil
+	}
+`, nameOf(value), pkg.Mangle(value))
+	}
+}
+
+func unpackPointer(buf *bytes.Buffer, typ *types.Pointer, opts pkg.Options) {
+	if opts.IsHandle(typ) {
+		fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+	return unpackHandle(p, %q).(%s)
+`, nameOf(typ.Elem()), nameOf(typ))
+		return
+	}
+	if basic, ok := typ.Elem().Underlying().(*types.Basic); ok {
+		if cond := isNAScalar(basic); cond != "" {
+			// NA scalar values are passed to Go as nil.
+			fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 || %s {
+		return nil
+	}
+	r := unpackSEXP%s(p)
+	return &r
+`, cond, pkg.Mangle(typ.Elem()))
+			return
+		}
+	}
 	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
 		return nil
 	}
@@ -230,7 +490,15 @@ func unpackPointer(buf *bytes.Buffer, typ *types.Pointer) {
 `, pkg.Mangle(typ.Elem()))
 }
 
-func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
+func unpackNullable(buf *bytes.Buffer, typ *types.Named, elem *types.Basic) {
+	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 || %[1]s {
+		return %[2]s{}
+	}
+	return %[2]s{%[3]s: unpackSEXP%[4]s(p), Valid: true}
+`, isNAScalar(elem), nameOf(typ), nullableField(typ), pkg.Mangle(elem))
+}
+
+func unpackSlice(buf *bytes.Buffer, typ *types.Slice, opts pkg.Options) {
 	// TODO(kortschak): Use unsafe.Slice when it exists.
 
 	fmt.Fprintf(buf, `	if C.Rf_isNull(p) != 0 {
@@ -246,8 +514,9 @@ func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
 			// Maximum length array type for this element type.
 			type a [1 << 47]int32
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
-	return (*[%d]%s)(unsafe.Pointer(C.INTEGER(p)))[:n]
-`, len(&a{}), nameOf(elem))
+	r := (*[%d]%s)(unsafe.Pointer(C.INTEGER(p)))[:n]
+%s	return r
+`, len(&a{}), nameOf(elem), naScan(opts, elem))
 			return
 		case types.Int, types.Int16, types.Uint, types.Uint16:
 			// Maximum length array type for this element type.
@@ -255,10 +524,13 @@ func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
 	r := make(%s, n)
 	for i, v := range (*[%d]%s)(unsafe.Pointer(C.INTEGER(p)))[:n] {
-		r[i] = %s(v)
+%s		r[i] = %s(v)
 	}
 	return r
-`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Int32]), elem)
+`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Int32]), naCheck(opts, elem, "v", "i", "\t\t"), elem)
+			return
+		case types.Int64, types.Uint64:
+			fmt.Fprintf(buf, "\treturn unpack%s(p)\n", int64Suffix(elem))
 			return
 		case types.Int8, types.Uint8:
 			// Maximum length array type for this element type.
@@ -273,17 +545,18 @@ func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
 	r := make(%s, n)
 	for i, v := range (*[%d]%s)(unsafe.Pointer(C.REAL(p)))[:n] {
-		r[i] = %s(v)
+%s		r[i] = %s(v)
 	}
 	return r
-`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Float64]), elem)
+`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Float64]), naCheck(opts, elem, "v", "i", "\t\t"), elem)
 			return
 		case types.Float64:
 			// Maximum length array type for this element type.
 			type a [1 << 46]float64
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
-	return (*[%d]%s)(unsafe.Pointer(C.REAL(p)))[:n]
-`, len(&a{}), nameOf(elem))
+	r := (*[%d]%s)(unsafe.Pointer(C.REAL(p)))[:n]
+%s	return r
+`, len(&a{}), nameOf(elem), naScan(opts, elem))
 			return
 		case types.Complex64:
 			// Maximum length array type for this element type.
@@ -291,37 +564,38 @@ func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
 	r := make(%s, n)
 	for i, v := range (*[%d]%s)(unsafe.Pointer(C.COMPLEX(p)))[:n] {
-		r[i] = %s(v)
+%s		r[i] = %s(v)
 	}
 	return r
-`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Complex128]), elem)
+`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Complex128]), naCheck(opts, elem, "v", "i", "\t\t"), elem)
 			return
 		case types.Complex128:
 			// Maximum length array type for this element type.
 			type a [1 << 45]complex128
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
-	return (*[%d]%s)(unsafe.Pointer(C.COMPLEX(p)))[:n]
-`, len(&a{}), nameOf(elem))
+	r := (*[%d]%s)(unsafe.Pointer(C.COMPLEX(p)))[:n]
+%s	return r
+`, len(&a{}), nameOf(elem), naScan(opts, elem))
 			return
 		case types.Bool:
 			// Maximum length array type for this element type.
 			type a [1 << 47]int32
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
 	r := make(%s, n)
-	for i, b := range (*[%d]%s)(unsafe.Pointer(C.BOOL(p)))[:n] {
-		r[i] = (b == 1)
+	for i, b := range (*[%d]%s)(unsafe.Pointer(C.LOGICAL(p)))[:n] {
+%s		r[i] = (b == 1)
 	}
 	return r
-`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Int32]))
+`, nameOf(typ), len(&a{}), nameOf(types.Typ[types.Int32]), naCheck(opts, elem, "b", "i", "\t\t"))
 			return
 		case types.String:
 			fmt.Fprintf(buf, `	n := C.Rf_xlength(p)
 	r := make(%s, n)
 	for i := range r {
-		r[i] = %s(C.R_gostring(p, C.R_xlen_t(i)))
+%s		r[i] = %s(C.R_gostring(p, C.R_xlen_t(i)))
 	}
 	return r
-`, nameOf(typ), nameOf(elem))
+`, nameOf(typ), naCheck(opts, elem, "C.STRING_ELT(p, C.R_xlen_t(i))", "i", "\t\t"), nameOf(elem))
 			return
 		}
 	}
@@ -334,30 +608,242 @@ func unpackSlice(buf *bytes.Buffer, typ *types.Slice) {
 `, nameOf(typ), pkg.Mangle(elem))
 }
 
-func unpackStruct(buf *bytes.Buffer, typ *types.Struct) {
-	n := typ.NumFields()
+func unpackStruct(buf *bytes.Buffer, typ types.Type, s *types.Struct, opts pkg.Options) {
+	var fields, attrs []pkg.Field
+	for _, f := range opts.Fields(s) {
+		if f.Attr {
+			attrs = append(attrs, f)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	if payload := opts.Payload(s); payload != nil {
+		fmt.Fprintf(buf, `	var r %s
+	r.%s = unpackSEXP%s(p)
+`, nameOf(typ), payload.Path, pkg.Mangle(payload.Var.Type()))
+		if _, _, nilable := rTypeOf(opts, payload.Var.Type()); nilable {
+			// NULL has no attributes.
+			fmt.Fprint(buf, `	if C.Rf_isNull(p) != 0 {
+		return r
+	}
+`)
+		}
+	} else {
+		unpackList(buf, typ, fields)
+	}
+	if len(attrs) != 0 {
+		fmt.Fprintln(buf, "\tvar a C.SEXP")
+	}
+	for _, f := range attrs {
+		fmt.Fprintf(buf, `	key_%[1]s := C.CString("%[1]s")
+	defer C.free(unsafe.Pointer(key_%[1]s))
+	a = C.Rf_getAttrib(p, C.Rf_install(key_%[1]s))
+`, f.Name)
+		if f.OmitEmpty || f.Via != nil {
+			// Empty omitempty fields and fields behind
+			// nil struct pointers may be missing.
+			fmt.Fprint(buf, "\tif a != C.R_NilValue {\n")
+			allocVia(buf, f)
+			fmt.Fprintf(buf, `		r.%s = unpackSEXP%s(a)
+	}
+`, f.Path, pkg.Mangle(f.Var.Type()))
+			continue
+		}
+		fmt.Fprintf(buf, `	if a == C.R_NilValue {
+		panic("no attribute for field: %s")
+	}
+	r.%[1]s = unpackSEXP%s(a)
+`, f.Path, pkg.Mangle(f.Var.Type()))
+	}
+	fmt.Fprintln(buf, "\treturn r")
+}
+
+// unpackList writes the Go source for unpacking the given fields of r,
+// of type typ, from the named R list p.
+func unpackList(buf *bytes.Buffer, typ types.Type, fields []pkg.Field) {
+	var required int
+	for _, f := range fields {
+		if !f.OmitEmpty && f.Via == nil {
+			required++
+		}
+	}
 	fmt.Fprintf(buf, `	switch n := C.Rf_xlength(p); {
 	case n < %[1]d:
 		panic(`+"`missing list element for %[2]s`"+`)
-	case n > %[1]d:
+	case n > %[3]d:
 		err := C.CString(`+"`extra list element ignored for %[2]s`"+`)
 		C.R_error(err)
 		C.free(unsafe.Pointer(err))
 	}
 	var r %[2]s
 	var i C.int
-`, n, nameOf(typ))
-	for i := 0; i < n; i++ {
-		f := typ.Field(i)
-
+`, required, nameOf(typ), len(fields))
+	for _, f := range fields {
 		fmt.Fprintf(buf, `	key_%s := C.CString("%[1]s")
 	defer C.free(unsafe.Pointer(key_%[1]s))
 	i = C.getListElementIndex(p, key_%[1]s)
+`, f.Name)
+		if f.OmitEmpty || f.Via != nil {
+			// Empty omitempty fields and fields behind
+			// nil struct pointers may be missing.
+			fmt.Fprint(buf, "\tif i >= 0 {\n")
+			allocVia(buf, f)
+			fmt.Fprintf(buf, `		r.%s = unpackSEXP%s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+	}
+`, f.Path, pkg.Mangle(f.Var.Type()))
+			continue
+		}
+		fmt.Fprintf(buf, `	if i < 0 {
+		panic("no list element name for field: %s")
+	}
+	r.%[1]s = unpackSEXP%s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+`, f.Path, pkg.Mangle(f.Var.Type()))
+	}
+}
+
+// allocVia writes the Go source for allocating the nil struct pointers
+// of r that the field f is flattened through.
+func allocVia(buf *bytes.Buffer, f pkg.Field) {
+	for _, v := range f.Via {
+		fmt.Fprintf(buf, `		if r.%[1]s == nil {
+			r.%[1]s = new(%[2]s)
+		}
+`, v.Path, nameOf(v.Var.Type().Underlying().(*types.Pointer).Elem()))
+	}
+}
+
+func unpackFrame(buf *bytes.Buffer, typ types.Type, kind pkg.FrameKind, opts pkg.Options) {
+	cols := opts.FrameColumns(typ)
+	var fields []pkg.Field
+	switch kind {
+	case pkg.RowFrame:
+		fields = opts.Fields(typ.(*types.Slice).Elem().Underlying().(*types.Struct))
+		fmt.Fprint(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+`)
+	case pkg.ColumnFrame:
+		fields = opts.Fields(typ.(*types.Struct))
+	default:
+		panic(fmt.Sprintf("unhandled data.frame kind: %d", kind))
+	}
+
+	fmt.Fprintln(buf, "\tvar i C.int")
+	for i, f := range fields {
+		var typ types.Type = cols[i]
+		if kind == pkg.ColumnFrame {
+			typ = f.Var.Type()
+		}
+		fmt.Fprintf(buf, `	key%[1]d := C.CString("%[2]s")
+	defer C.free(unsafe.Pointer(key%[1]d))
+	i = C.getListElementIndex(p, key%[1]d)
 	if i < 0 {
-		panic("no list element name for field: %[2]s")
+		panic("no data.frame column for field: %[3]s")
+	}
+	col%[1]d := unpackSEXP%[4]s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
+`, i, f.Name, f.Path, pkg.Mangle(typ))
+	}
+	if kind == pkg.RowFrame || len(cols) > 1 {
+		// The length is only needed to make the rows
+		// and to check the other columns.
+		fmt.Fprintln(buf, "\tn := len(col0)")
 	}
-	r.%[2]s = unpackSEXP%s(C.VECTOR_ELT(p, C.R_xlen_t(i)))
-`, targetFieldName(typ, i), f.Name(), pkg.Mangle(f.Type()))
+	for i := 1; i < len(cols); i++ {
+		fmt.Fprintf(buf, `	if len(col%[1]d) != n {
+		panic(fmt.Sprintf("data.frame column %[2]s has length %%d, want %%d", len(col%[1]d), n))
+	}
+`, i, fields[i].Name)
+	}
+
+	switch kind {
+	case pkg.RowFrame:
+		fmt.Fprintf(buf, `	r := make(%s, n)
+	for j := range r {
+`, nameOf(typ))
+		for i, f := range fields {
+			fmt.Fprintf(buf, "\t\tr[j].%s = %s(col%d[j])\n", f.Path, nameOf(f.Var.Type()), i)
+		}
+		fmt.Fprintln(buf, "\t}")
+	case pkg.ColumnFrame:
+		fmt.Fprintf(buf, "\tvar r %s\n", nameOf(typ))
+		for i, f := range fields {
+			fmt.Fprintf(buf, "\tr.%s = col%d\n", f.Path, i)
+		}
 	}
 	fmt.Fprintln(buf, "\treturn r")
 }
+
+func unpackMatrix(buf *bytes.Buffer, typ types.Type, kind pkg.MatrixKind) {
+	elem := pkg.MatrixElem(typ)
+	var (
+		data string
+		size int
+	)
+	switch elem.Kind() {
+	case types.Int32:
+		// Maximum length array type for this element type.
+		type a [1 << 47]int32
+		data, size = "INTEGER", len(&a{})
+	case types.Float64:
+		// Maximum length array type for this element type.
+		type a [1 << 46]float64
+		data, size = "REAL", len(&a{})
+	default:
+		panic(fmt.Sprintf("unhandled matrix element type: %s", elem))
+	}
+
+	if kind == pkg.SliceMatrix {
+		fmt.Fprint(buf, `	if C.Rf_isNull(p) != 0 {
+		return nil
+	}
+`)
+	}
+	fmt.Fprintf(buf, `	if C.Rf_isMatrix(p) == 0 {
+		panic("argument is not a matrix")
+	}
+	rows := int(C.Rf_nrows(p))
+	cols := int(C.Rf_ncols(p))
+	s := (*[%d]%s)(unsafe.Pointer(C.%s(p)))[:rows*cols]
+`, size, nameOf(elem), data)
+
+	switch kind {
+	case pkg.SliceMatrix:
+		fmt.Fprintf(buf, `	r := make(%s, rows)
+	for i := range r {
+		row := make(%s, cols)
+		for j := range row {
+			row[j] = s[i+j*rows]
+		}
+		r[i] = row
+	}
+	return r
+`, nameOf(typ), nameOf(types.NewSlice(elem)))
+
+	case pkg.GeneralColsMatrix:
+		fmt.Fprintf(buf, "\treturn %s{Rows: rows, Cols: cols, Data: s, Stride: rows}\n", nameOf(typ))
+
+	case pkg.GeneralMatrix:
+		fmt.Fprintf(buf, `	r := %s{Rows: rows, Cols: cols, Data: make([]float64, len(s)), Stride: cols}
+	for j := 0; j < cols; j++ {
+		for i, v := range s[j*rows : (j+1)*rows] {
+			r.Data[i*cols+j] = v
+		}
+	}
+	return r
+`, nameOf(typ))
+
+	case pkg.DenseMatrix:
+		fmt.Fprintf(buf, `	var r %s
+	if rows == 0 || cols == 0 {
+		return r
+	}
+	data := make([]float64, len(s))
+	for j := 0; j < cols; j++ {
+		for i, v := range s[j*rows : (j+1)*rows] {
+			data[i*cols+j] = v
+		}
+	}
+	return *%s.NewDense(rows, cols, data)
+`, nameOf(typ), typ.(*types.Named).Obj().Pkg().Name())
+	}
+}
@@ -13,6 +13,7 @@ func NamespaceTemplate(words []string, exported func(string) bool) *template.Tem
 	}).Parse(`# Code generated by rgnonomic/rgo; DO NOT EDIT.
 
 useDynLib({{$.Pkg.Name}})
-{{range $func := .Funcs}}{{if exported $func.Func.Name}}export({{snake $func.Func.Name}})
+{{range $func := .Funcs}}{{if exported $func.QualifiedName}}export({{snake $func.Ident}})
+{{end}}{{end}}{{range $c := .Consts}}{{if exported $c.Name}}export({{snake $c.Name}})
 {{end}}{{end}}`))
 }
@@ -6,8 +6,10 @@ package codegen
 
 import (
 	"fmt"
+	"go/constant"
 	"go/types"
 	"path"
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
@@ -25,42 +27,103 @@ func RCallTemplate(words []string, exported func(string) bool) *template.Templat
 		"exported":  exported,
 		"varsOf":    varsOf,
 		"names":     names,
+		"formals":   formals,
 		"doc":       doc,
 		"typecheck": typeCheck,
 		"returns":   returns,
 		"seelso":    seelso,
 		"replace":   strings.ReplaceAll,
+		"literal":   literal,
 	}).Parse(`{{$pkg := .Pkg}}# Code generated by rgnonomic/rgo; DO NOT EDIT.
 
 #' @useDynLib {{base $pkg.Path}}{{range $func := .Funcs}}
-{{$params := varsOf $func.Signature.Params}}
-#' {{snake $func.Func.Name}}
+{{$params := $func.Params}}
+#' {{snake $func.Ident}}
 #'
-#' {{replace $func.FuncDecl.Doc.Text "\n" "\n#' "}}
-{{range $p := $params}}{{doc $p}}
-{{end}}{{returns $func.Signature.Results}}{{seelso $pkg $func.Func}}
-{{if exported $func.Func.Name}}#' @export
+#' {{replace $func.FuncDecl.Doc.Text "\n" "\n#' "}}{{if $func.TypeArgs}}Wraps the instantiation {{$func.InstanceName}}.
+#' {{else if $func.Object}}{{if $params}}Sets{{else}}Returns{{end}} the value of {{$func.Object.Name}}.
+#' {{end}}
+{{range $p := $params}}{{doc $.Options $func $p}}
+{{end}}{{returns $.Options $func.Signature.Results}}{{seelso $pkg $func.QualifiedName}}
+{{if exported $func.QualifiedName}}#' @export
 {{end -}}
-{{- snake $func.Func.Name}} <- function({{names false $params}}) {
-{{range $p := $params}}{{typecheck $p -}}
-{{- end}}	.Call("{{snake $func.Func.Name}}"{{names true $params}}, PACKAGE = "{{base $pkg.Path}}")
-}{{end}}
+{{- snake $func.Ident}} <- function({{formals $func}}) {
+{{range $i, $p := $params}}{{typecheck $.Options $func $i $p -}}
+{{- end}}	.Call("{{snake $func.Ident}}"{{names true $params}}, PACKAGE = "{{base $pkg.Path}}")
+}{{end}}{{range $c := .Consts}}
+
+#' {{snake $c.Name}}
+#'
+#' {{replace $c.Doc.Text "\n" "\n#' "}}The value of {{$c.Name}}.
+#' 
+{{seelso $pkg $c.Name}}
+{{if exported $c.Name}}#' @export
+{{end -}}
+{{snake $c.Name}} <- {{literal $c.Const}}{{end}}
 `))
 }
 
-// doc returns an R documentation line for the variable v.
-func doc(v *types.Var) string {
-	return fmt.Sprintf("#' @param %s is %s", v.Name(), article(rDocFor(v.Type()), false))
+// literal returns the R literal for the value of c.
+func literal(c *types.Const) string {
+	val := c.Val()
+	switch info := types.Default(c.Type()).(*types.Basic).Info(); {
+	case info&types.IsBoolean != 0:
+		if constant.BoolVal(val) {
+			return "TRUE"
+		}
+		return "FALSE"
+	case info&types.IsString != 0:
+		return strconv.Quote(constant.StringVal(val))
+	case info&types.IsInteger != 0:
+		v, _ := constant.Int64Val(val)
+		return fmt.Sprintf("%dL", v)
+	case info&types.IsFloat != 0:
+		return double(val)
+	case info&types.IsComplex != 0:
+		return fmt.Sprintf("complex(real = %s, imaginary = %s)", double(constant.Real(val)), double(constant.Imag(val)))
+	default:
+		panic(fmt.Sprintf("unhandled constant type: %s", c.Type()))
+	}
+}
+
+// double returns the shortest R double literal for the numeric constant
+// value val.
+func double(val constant.Value) string {
+	v, _ := constant.Float64Val(val)
+	return strconv.FormatFloat(v, 'g', -1, 64)
 }
 
-// seealso returns an @seealso documentation line linking to the fn's
-// godoc.org documentation.
-func seelso(pkg *types.Package, fn *types.Func) string {
-	return fmt.Sprintf("#' @seelso <https://godoc.org/%s#%s>", pkg.Path(), fn.Name())
+// formals returns the formal arguments of the R function wrapping fn.
+// The variadic parameter of fn is passed as the R ... argument.
+func formals(fn pkg.FuncInfo) string {
+	params := fn.Params()
+	if fn.Variadic() == nil {
+		return names(false, params)
+	}
+	params = params[:len(params)-1]
+	if len(params) == 0 {
+		return "..."
+	}
+	return names(false, params) + ", ..."
+}
+
+// doc returns an R documentation line for the parameter v of fn.
+func doc(opts pkg.Options, fn pkg.FuncInfo, v *types.Var) string {
+	if v == fn.Variadic() {
+		elem := v.Type().(*types.Slice).Elem()
+		return fmt.Sprintf("#' @param ... are zero or more values, each %s", article(rDocFor(opts, elem), false))
+	}
+	return fmt.Sprintf("#' @param %s is %s", v.Name(), article(rDocFor(opts, v.Type()), false))
+}
+
+// seealso returns an @seealso documentation line linking to the godoc.org
+// documentation for the function or method with the given qualified name.
+func seelso(pkg *types.Package, name string) string {
+	return fmt.Sprintf("#' @seelso <https://godoc.org/%s#%s>", pkg.Path(), name)
 }
 
 // returns returns an R documentation table for the returned values in t.
-func returns(t *types.Tuple) string {
+func returns(opts pkg.Options, t *types.Tuple) string {
 	if t.Len() == 0 {
 		return ""
 	}
@@ -69,7 +132,7 @@ func returns(t *types.Tuple) string {
 	case 0:
 	case 1:
 		v := t.At(0)
-		doc := rDocFor(v.Type())
+		doc := rDocFor(opts, v.Type())
 		name := v.Name()
 		if name != "" {
 			name = ", " + name
@@ -79,7 +142,7 @@ func returns(t *types.Tuple) string {
 		fmt.Fprintf(&buf, "#' @return A structured value containing:\n")
 		for i := 0; i < t.Len(); i++ {
 			v := t.At(i)
-			doc := rDocFor(v.Type())
+			doc := rDocFor(opts, v.Type())
 			name := v.Name()
 			if name == "" {
 				name = fmt.Sprintf("r%d", i)
@@ -91,12 +154,75 @@ func returns(t *types.Tuple) string {
 }
 
 // rDocFor returns a string describing the R type based on the given Go type.
-func rDocFor(typ types.Type) string {
-	rtyp, length, _ := rTypeOf(typ)
+func rDocFor(opts pkg.Options, typ types.Type) string {
+	if pkg.IsDynamic(typ) {
+		return "value of any type"
+	}
+	if impls := pkg.Union(typ); impls != nil {
+		return fmt.Sprintf("value of class %s", strings.Join(variantClasses(opts, impls), " or "))
+	}
+	if class := opts.Class(typ); class != "" {
+		return fmt.Sprintf("%s of class %s", rDocFor(opts, typ.Underlying()), class)
+	}
+	if elem, _ := matrixOf(typ); elem != nil {
+		return fmt.Sprintf("%s matrix", basicRtype(opts, elem))
+	}
+	if dims, _ := pkg.ArrayDims(typ.Underlying()); dims != nil {
+		rtyp, _, _ := rTypeOf(opts, typ)
+		return fmt.Sprintf("%s array with dim c(%s)", rtyp, dimList(dims))
+	}
+	if opts.IsHandle(typ) {
+		return fmt.Sprintf("handle to %s value", article(handleClass(typ), false))
+	}
+	if elem := pkg.Nullable(typ); elem != nil {
+		return fmt.Sprintf("scalar %s or NA", basicRtype(opts, elem))
+	}
+	if kind := opts.Temporal(typ); kind != pkg.NotTemporal {
+		return fmt.Sprintf("scalar %s", temporalRtype(kind))
+	}
+	if opts.Text(typ) != pkg.NotText {
+		return "scalar character"
+	}
+	if kind := pkg.Arrow(typ); kind != pkg.NotArrow {
+		return fmt.Sprintf("external pointer to an Arrow C data interface %s", arrowName(kind))
+	}
+	if pkg.Image(typ) != pkg.NotImage {
+		if opts.NativeRaster {
+			return "image as a nativeRaster"
+		}
+		return "image as a double array with dim c(height, width, 4)"
+	}
+	if kind, _, slice := pkg.BigOf(typ); kind != pkg.NotBig {
+		if slice {
+			return fmt.Sprintf("%s vector", bigRtype(opts, kind))
+		}
+		return fmt.Sprintf("scalar %s", bigRtype(opts, kind))
+	}
+	switch u := typ.Underlying(); opts.Frame(u) {
+	case pkg.RowFrame:
+		return fmt.Sprintf("data.frame with rows corresponding to %s", u.(*types.Slice).Elem())
+	case pkg.ColumnFrame:
+		return fmt.Sprintf("data.frame with columns corresponding to %s", u)
+	}
+	if pkg.Map(typ.Underlying()) == pkg.KeyedMap {
+		return "list with keys and values elements"
+	}
+	rtyp, length, _ := rTypeOf(opts, typ)
 	switch typ := typ.Underlying().(type) {
 	case *types.Pointer:
-		return rDocFor(typ.Elem())
+		return rDocFor(opts, typ.Elem())
+	case *types.Signature:
+		return fmt.Sprintf("function corresponding to %s", typ)
 	case *types.Struct:
+		if payload := opts.Payload(typ); payload != nil {
+			var attrs []string
+			for _, f := range opts.Fields(typ) {
+				if f.Attr {
+					attrs = append(attrs, f.Name)
+				}
+			}
+			return fmt.Sprintf("%s with %s attributes", rDocFor(opts, payload.Var.Type()), strings.Join(attrs, ", "))
+		}
 		return fmt.Sprintf("%s corresponding to %s", rtyp, typ)
 	default:
 		switch {
@@ -131,79 +257,380 @@ func article(noun string, capital bool) string {
 	}
 }
 
-func typeCheck(p *types.Var) string {
+// typeCheck returns R code checking the ith parameter, p, of fn.
+func typeCheck(opts pkg.Options, fn pkg.FuncInfo, i int, p *types.Var) string {
+	if p == fn.Variadic() {
+		return dotsCheck(opts, p)
+	}
+	if i == 0 && fn.Receiver() != nil {
+		return recvCheck(opts, p)
+	}
+	return paramCheck(opts, p)
+}
+
+// recvCheck returns R code checking the receiver, p, of a method. Methods
+// may not be called on NULL.
+func recvCheck(opts pkg.Options, p *types.Var) string {
+	if opts.IsHandle(p.Type()) {
+		return fmt.Sprintf(`	if (!inherits(%[2]s, "%[1]s")) {
+		stop("Argument '%[2]s' must be a '%[1]s' handle.")
+	}
+`, handleClass(p.Type()), p.Name())
+	}
+	if ptr, ok := p.Type().(*types.Pointer); ok {
+		// Check as the pointed-to type, which is not nilable.
+		p = types.NewVar(p.Pos(), p.Pkg(), p.Name(), ptr.Elem())
+	}
+	return paramCheck(opts, p)
+}
+
+// dotsCheck returns R code collecting the ... arguments into a list named
+// for the variadic parameter p and checking each of its elements.
+func dotsCheck(opts pkg.Options, p *types.Var) string {
+	// The loop variable is dotted so it cannot shadow another parameter.
+	elem := types.NewVar(p.Pos(), p.Pkg(), ".arg", p.Type().(*types.Slice).Elem())
+	check := paramCheck(opts, elem)
+	check = strings.ReplaceAll(check, "Argument '.arg'", "Each argument in '...'")
+	check = strings.ReplaceAll(check, "\n\t", "\n\t\t")
+	return fmt.Sprintf("\t%s <- list(...)\n\tfor (.arg in %[1]s) {\n%s\t}\n", p.Name(), "\t"+check)
+}
+
+func paramCheck(opts pkg.Options, p *types.Var) string {
 	typ := p.Type()
 	if typ, ok := typ.(*types.Basic); ok && typ.Kind() == types.UnsafePointer {
 		return ""
 	}
-	rtyp, length, nilable := rTypeOf(typ)
+	if pkg.IsSEXP(typ) {
+		return ""
+	}
+	if pkg.IsDynamic(typ) {
+		// Conversion is checked at run time.
+		return ""
+	}
+	if elem, nilable := matrixOf(typ); elem != nil {
+		rtyp := basicRtype(opts, elem)
+		if nilable {
+			return fmt.Sprintf(`	if (!is.null(%[2]s) && (!is.matrix(%[2]s) || !is.%[1]s(%[2]s))) {
+		stop("Argument '%[2]s' must be a '%[1]s' matrix or NULL.")
+	}
+`, rtyp, p.Name())
+		}
+		return fmt.Sprintf(`	if (!is.matrix(%[2]s) || !is.%[1]s(%[2]s)) {
+		stop("Argument '%[2]s' must be a '%[1]s' matrix.")
+	}
+`, rtyp, p.Name())
+	}
+	if dims, _ := pkg.ArrayDims(typ.Underlying()); dims != nil {
+		rtyp, _, _ := rTypeOf(opts, typ)
+		return fmt.Sprintf(`	if (!%[3]s || !identical(dim(%[2]s), c(%[4]s))) {
+		stop("Argument '%[2]s' must be a '%[1]s' array with dim c(%[5]s).")
+	}
+`, rtyp, p.Name(), rIs(rtyp, p.Name()), strings.ReplaceAll(dimList(dims), ",", "L,")+"L", dimList(dims))
+	}
+	if kind := pkg.Arrow(typ); kind != pkg.NotArrow {
+		return fmt.Sprintf(`	if (!is.null(%[1]s) && typeof(%[1]s) != "externalptr") {
+		stop("Argument '%[1]s' must be an external pointer to an %[2]s or NULL.")
+	}
+`, p.Name(), arrowName(kind))
+	}
+	if pkg.Image(typ) != pkg.NotImage {
+		return fmt.Sprintf(`	if (!is.null(%[1]s) && !inherits(%[1]s, c("nativeRaster", "raster")) && !(is.double(%[1]s) && length(dim(%[1]s)) %%in%% 2:3)) {
+		stop("Argument '%[1]s' must be a 'double' array, raster or nativeRaster or NULL.")
+	}
+`, p.Name())
+	}
+	if opts.IsHandle(typ) {
+		return fmt.Sprintf(`	if (!is.null(%[2]s) && !inherits(%[2]s, "%[1]s")) {
+		stop("Argument '%[2]s' must be a '%[1]s' handle or NULL.")
+	}
+`, handleClass(typ), p.Name())
+	}
+	if impls := pkg.Union(typ); impls != nil {
+		classes := variantClasses(opts, impls)
+		quoted := make([]string, len(classes))
+		for i, c := range classes {
+			quoted[i] = fmt.Sprintf("%q", c)
+		}
+		return fmt.Sprintf(`	if (!is.null(%[1]s) && !inherits(%[1]s, c(%[2]s))) {
+		stop("Argument '%[1]s' must be of class '%[3]s' or NULL.")
+	}
+`, p.Name(), strings.Join(quoted, ", "), strings.Join(classes, "' or '"))
+	}
+	rtyp, length, nilable := rTypeOf(opts, typ)
+	is := rIs(rtyp, p.Name())
+	desc := fmt.Sprintf("of type '%s'", rtyp)
+	if kind, _, _ := pkg.BigOf(typ); kind == pkg.BigInt || kind == pkg.BigRat {
+		// Either representation may be passed to Go.
+		is, desc = bigRIs(kind, p.Name())
+	}
 	var check string
 	if nilable {
-		check = fmt.Sprintf(`	if (!is.%[1]s(%[2]s) && !is.null(%[2]s)) {
-		stop("Argument '%[2]s' must be of type '%[1]s' or NULL.")
+		check = fmt.Sprintf(`	if (!%[3]s && !is.null(%[2]s)) {
+		stop("Argument '%[2]s' must be %[1]s or NULL.")
 	}
-`, rtyp, p.Name())
+`, desc, p.Name(), is)
 	} else {
-		check = fmt.Sprintf(`	if (!is.%[1]s(%[2]s)) {
-		stop("Argument '%[2]s' must be of type '%[1]s'.")
+		check = fmt.Sprintf(`	if (!%[3]s) {
+		stop("Argument '%[2]s' must be %[1]s.")
 	}
-`, rtyp, p.Name())
+`, desc, p.Name(), is)
+	}
+	if class := classOf(opts, typ); class != "" {
+		null := ""
+		if nilable {
+			null = fmt.Sprintf("!is.null(%s) && ", p.Name())
+		}
+		check += fmt.Sprintf(`	if (%[3]s!inherits(%[2]s, %[1]q)) {
+		stop("Argument '%[2]s' must be of class '%[1]s'.")
+	}
+`, class, p.Name(), null)
 	}
 	if length > 0 {
 		var plural string
 		if length != 1 {
 			plural = "s"
 		}
-		check += fmt.Sprintf(`	if (length(%[1]s) != %[2]d) {
-		stop("Argument '%[1]s' must have %d element%s.")
+		null := ""
+		if nilable {
+			null = fmt.Sprintf("!is.null(%s) && ", p.Name())
+		}
+		check += fmt.Sprintf(`	if (%[5]slength(%[1]s) != %[2]d) {
+		stop("Argument '%[1]s' must have %[3]d element%[4]s.")
 	}
-`, p.Name(), length, plural)
+`, p.Name(), length, length, plural, null)
 	}
 	return check
 }
 
-func rTypeOf(typ types.Type) (rtyp string, length int64, nilable bool) {
+// rIs returns an R expression that checks whether the value x is of the
+// R type rtyp.
+func rIs(rtyp, x string) string {
+	switch rtyp {
+	case string(pkg.Integer64):
+		return fmt.Sprintf("bit64::is.integer64(%s)", x)
+	case "POSIXct", "difftime", "Date":
+		return fmt.Sprintf("inherits(%s, %q)", x, rtyp)
+	case "factor":
+		return fmt.Sprintf("(is.factor(%[1]s) || is.character(%[1]s))", x)
+	}
+	return fmt.Sprintf("is.%s(%s)", rtyp, x)
+}
+
+// bigRIs returns an R expression that checks whether the value x is in
+// either R representation of the math/big number type kind, and a
+// description of the representations.
+func bigRIs(kind pkg.BigKind, x string) (is, desc string) {
+	switch kind {
+	case pkg.BigInt:
+		return fmt.Sprintf(`(is.character(%[1]s) || inherits(%[1]s, "bigz"))`, x),
+			"of type 'character' or class 'bigz'"
+	case pkg.BigRat:
+		return fmt.Sprintf(`(is.character(%[1]s) || inherits(%[1]s, c("bigq", "bigz")))`, x),
+			"of type 'character' or class 'bigq' or 'bigz'"
+	default:
+		panic(fmt.Sprintf("unhandled math/big type: %d", kind))
+	}
+}
+
+// bigRtype returns the R type of values of the math/big number type
+// kind.
+func bigRtype(opts pkg.Options, kind pkg.BigKind) string {
+	if !opts.GMP {
+		return "character"
+	}
+	switch kind {
+	case pkg.BigInt:
+		return "bigz"
+	case pkg.BigRat:
+		return "bigq"
+	default:
+		return "character"
+	}
+}
+
+// temporalRtype returns the R class corresponding to the time class kind.
+func temporalRtype(kind pkg.TemporalKind) string {
+	switch kind {
+	case pkg.Time:
+		return "POSIXct"
+	case pkg.Duration:
+		return "difftime"
+	case pkg.Date:
+		return "Date"
+	default:
+		panic(fmt.Sprintf("unhandled time class: %d", kind))
+	}
+}
+
+func rTypeOf(opts pkg.Options, typ types.Type) (rtyp string, length int64, nilable bool) {
 	if pkg.IsError(typ) {
 		return "character", -1, true
 	}
+	if pkg.IsSEXP(typ) {
+		return "SEXP", 1, false
+	}
+	if pkg.Union(typ) != nil || pkg.IsDynamic(typ) {
+		return "", -1, true
+	}
+	if elem := pkg.Nullable(typ); elem != nil {
+		return basicRtype(opts, elem), 1, true
+	}
+	if kind, ptr, slice := pkg.BigOf(typ); kind != pkg.NotBig {
+		if slice {
+			return bigRtype(opts, kind), -1, true
+		}
+		return bigRtype(opts, kind), 1, ptr
+	}
+	if pkg.Image(typ) != pkg.NotImage {
+		return imageRtype(opts), -1, true
+	}
+	if pkg.Arrow(typ) != pkg.NotArrow {
+		return "externalptr", -1, true
+	}
+	if kind := opts.Temporal(typ); kind != pkg.NotTemporal {
+		return temporalRtype(kind), 1, false
+	}
+	if pkg.Enum(typ) != nil {
+		return "factor", 1, false
+	}
+	if opts.Text(typ) != pkg.NotText {
+		return "character", 1, false
+	}
+	if dims, elem := pkg.ArrayDims(typ.Underlying()); dims != nil {
+		length = 1
+		for _, d := range dims {
+			length *= d
+		}
+		if elem.Kind() == types.Uint8 || elem.Kind() == types.Int8 {
+			return "raw", length, false
+		}
+		return basicRtype(opts, elem), length, false
+	}
+	switch opts.Frame(typ.Underlying()) {
+	case pkg.RowFrame:
+		return "data.frame", -1, true
+	case pkg.ColumnFrame:
+		return "data.frame", -1, false
+	}
 	switch typ := typ.Underlying().(type) {
 	case *types.Pointer:
-		rtyp, length, _ = rTypeOf(typ.Elem())
+		rtyp, length, _ = rTypeOf(opts, typ.Elem())
 		return rtyp, length, true
 	case *types.Basic:
-		return basicRtype(typ), 1, false
+		return basicRtype(opts, typ), 1, false
 	case *types.Slice:
 		elem := typ.Elem()
+		if kind := opts.Temporal(elem); kind != pkg.NotTemporal {
+			return temporalRtype(kind), -1, true
+		}
+		if pkg.Enum(elem) != nil {
+			return "factor", -1, true
+		}
+		if opts.Text(elem) != pkg.NotText {
+			return "character", -1, true
+		}
 		if etyp, ok := elem.(*types.Basic); ok {
 			if etyp.Kind() == types.Uint8 || etyp.Kind() == types.Int8 {
 				return "raw", -1, true
 			}
-			return basicRtype(etyp), -1, true
+			return basicRtype(opts, etyp), -1, true
 		}
 		return "list", -1, false
 	case *types.Array:
 		elem := typ.Elem()
+		if kind := opts.Temporal(elem); kind != pkg.NotTemporal {
+			return temporalRtype(kind), typ.Len(), false
+		}
+		if pkg.Enum(elem) != nil {
+			return "factor", typ.Len(), false
+		}
+		if opts.Text(elem) != pkg.NotText {
+			return "character", typ.Len(), false
+		}
 		if etyp, ok := elem.(*types.Basic); ok {
 			if etyp.Kind() == types.Uint8 || etyp.Kind() == types.Int8 {
 				return "raw", typ.Len(), false
 			}
-			return basicRtype(etyp), typ.Len(), false
+			return basicRtype(opts, etyp), typ.Len(), false
 		}
 		return "list", -1, false
 	case *types.Map:
+		switch pkg.Map(typ) {
+		case pkg.KeyedMap:
+			return "list", -1, true
+		case pkg.SetMap:
+			rtyp, _, _ = rTypeOf(opts, types.NewSlice(typ.Key()))
+			return rtyp, -1, true
+		}
 		return "vector", -1, true
+	case *types.Signature:
+		return "function", -1, true
 	case *types.Struct:
+		if payload := opts.Payload(typ); payload != nil {
+			return rTypeOf(opts, payload.Var.Type())
+		}
 		return "list", -1, false
 	}
 	return "", -1, false
 }
 
-func basicRtype(typ *types.Basic) string {
+// classOf returns the S3 class of R values of typ, or of the values
+// pointed to if typ is a pointer type.
+func classOf(opts pkg.Options, typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	return opts.Class(typ)
+}
+
+// variantClasses returns the S3 classes of the sealed interface
+// implementations impls.
+func variantClasses(opts pkg.Options, impls []types.Type) []string {
+	classes := make([]string, len(impls))
+	for i, typ := range impls {
+		classes[i] = variantClass(opts, typ)
+	}
+	return classes
+}
+
+// matrixOf returns the element type of the R matrix corresponding to typ
+// and whether the matrix may be NULL. If typ is not a matrix type, elem
+// is nil.
+func matrixOf(typ types.Type) (elem *types.Basic, nilable bool) {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		elem, _ = matrixOf(ptr.Elem())
+		return elem, true
+	}
+	kind := pkg.Matrix(typ)
+	if kind == pkg.NotMatrix {
+		typ = typ.Underlying()
+		kind = pkg.Matrix(typ)
+	}
+	return pkg.MatrixElem(typ), kind == pkg.SliceMatrix
+}
+
+// imageRtype returns the R type of images returned to R.
+func imageRtype(opts pkg.Options) string {
+	if opts.NativeRaster {
+		return "nativeRaster"
+	}
+	return "double"
+}
+
+// handleClass returns the R class of handles to values of the pointer
+// type typ.
+func handleClass(typ types.Type) string {
+	return nameOf(typ.(*types.Pointer).Elem())
+}
+
+func basicRtype(opts pkg.Options, typ *types.Basic) string {
 	switch info := typ.Info(); {
 	case info&types.IsBoolean != 0:
 		return "logical"
 	case info&types.IsString != 0:
 		return "character"
+	case pkg.Is64Bit(typ):
+		return string(opts.Int64)
 	case info&types.IsInteger != 0:
 		return "integer"
 	case info&types.IsFloat != 0:
@@ -0,0 +1,248 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkg
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// Options holds options that change the mapping between Go and R types.
+type Options struct {
+	// Int64 is the R representation of Go int64 and uint64
+	// values. If Int64 is empty, functions using 64-bit
+	// integers are not wrapped.
+	Int64 Int64Mode
+
+	// NASentinel allows R NA values to be passed to
+	// Go as the value R uses to represent NA for the
+	// type, rather than raising an error. Logical NA
+	// values always raise an error.
+	NASentinel bool
+
+	// Date is the qualified name, "path/to/pkg.Type",
+	// of a date-only type to be exchanged with R as a
+	// Date. The type must be a struct with Year int,
+	// Month time.Month and Day int fields, for example
+	// cloud.google.com/go/civil.Date. If Date is empty,
+	// no type is treated as a date.
+	Date string
+
+	// JSONTags allows json struct tags to name, skip
+	// and set options for struct fields that do not
+	// have an rgo struct tag. Unexported fields are
+	// skipped as they are by encoding/json.
+	JSONTags bool
+
+	// Classes gives R values of named Go types an S3
+	// class of the package-qualified Go type name,
+	// "pkg.Type", and checks the class of values
+	// passed to Go.
+	Classes bool
+
+	// ClassNames maps qualified Go type names,
+	// "path/to/pkg.Type", to the S3 class of their
+	// R values. Types in ClassNames are given a class
+	// even if Classes is false.
+	ClassNames map[string]string
+
+	// GMP represents math/big Int and Rat values as
+	// gmp bigz and bigq vectors rather than character
+	// vectors. Values passed to Go may be in either
+	// representation.
+	GMP bool
+
+	// NativeRaster represents images returned to R as
+	// nativeRaster integer matrices rather than double
+	// arrays with dim c(height, width, 4). Images passed
+	// to Go may be in either representation or rasters.
+	NativeRaster bool
+
+	// TypeArgs maps the names of generic functions
+	// to the lists of type arguments used to
+	// instantiate them. Each type argument is a Go
+	// type expression evaluated in the scope of the
+	// function's source file, for example "float64"
+	// or "big.Int". Generic functions without type
+	// arguments are not wrapped.
+	TypeArgs map[string][][]string
+}
+
+// Int64Mode is an R representation of 64-bit integers.
+type Int64Mode string
+
+const (
+	// Integer64 represents 64-bit integers as bit64 integer64 vectors.
+	Integer64 Int64Mode = "integer64"
+
+	// Double represents 64-bit integers as double vectors. Values
+	// are checked to be exactly representable in both directions.
+	Double Int64Mode = "double"
+
+	// Character represents 64-bit integers as decimal strings.
+	Character Int64Mode = "character"
+)
+
+func (o Options) validate() error {
+	switch o.Int64 {
+	case "", Integer64, Double, Character:
+	default:
+		return fmt.Errorf("pkg: invalid int64 representation: %q", o.Int64)
+	}
+	if o.Date != "" && !strings.Contains(o.Date, ".") {
+		return fmt.Errorf("pkg: invalid date type name: %q", o.Date)
+	}
+	return nil
+}
+
+// Temporal returns the time class of typ, including the date-only type
+// named by o.Date.
+func (o Options) Temporal(typ types.Type) TemporalKind {
+	if o.isDate(typ) {
+		return Date
+	}
+	return Temporal(typ)
+}
+
+// Text returns how values of typ are exchanged with R using their text
+// form. Only named types with an underlying composite type are
+// exchanged as text, so that other named types keep their natural R
+// representation, and time and math/big types are handled specially.
+// The methods may have value or pointer receivers.
+func (o Options) Text(typ types.Type) TextKind {
+	named, ok := typ.(*types.Named)
+	if !ok || o.Temporal(named) != NotTemporal || Matrix(named) != NotMatrix ||
+		Nullable(named) != nil || Big(named) != NotBig {
+		return NotText
+	}
+	switch named.Underlying().(type) {
+	case *types.Array, *types.Map, *types.Slice, *types.Struct:
+	default:
+		return NotText
+	}
+	var (
+		ptr   = types.NewPointer(named)
+		bytes = types.NewSlice(types.Typ[types.Byte])
+		err   = types.Universe.Lookup("error").Type()
+		str   = types.Typ[types.String]
+	)
+	switch {
+	case hasMethod(ptr, "MarshalText", nil, []types.Type{bytes, err}) &&
+		hasMethod(ptr, "UnmarshalText", []types.Type{bytes}, []types.Type{err}):
+		return TextMarshaler
+	case hasMethod(ptr, "String", nil, []types.Type{str}):
+		return Stringer
+	}
+	return NotText
+}
+
+// isDate returns whether typ is the date-only type named by o.Date.
+func (o Options) isDate(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || o.Date == "" {
+		return false
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path()+"."+obj.Name() != o.Date {
+		return false
+	}
+	s, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	want := map[string]string{"Year": "int", "Month": "time.Month", "Day": "int"}
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		if want[f.Name()] == f.Type().String() {
+			delete(want, f.Name())
+		}
+	}
+	return len(want) == 0
+}
+
+// Is64Bit returns whether typ is an int64 or uint64 type other than
+// time.Duration or an enum-like type.
+func Is64Bit(typ types.Type) bool {
+	if Temporal(typ) == Duration || Enum(typ) != nil {
+		return false
+	}
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch basic.Kind() {
+	case types.Int64, types.Uint64:
+		return true
+	}
+	return false
+}
+
+// uses64Bit returns a 64-bit integer type used by typ, or nil if
+// typ does not use 64-bit integers.
+func (o Options) uses64Bit(typ types.Type) types.Type {
+	found := make(packers)
+	o.walk(found, typ, typ)
+	for _, t := range found.Types() {
+		if Is64Bit(t) {
+			return t
+		}
+		if s, ok := t.Underlying().(*types.Slice); ok && Is64Bit(s.Elem()) {
+			return t
+		}
+		if sig, ok := t.Underlying().(*types.Signature); ok {
+			if typ := o.uses64Bit(sig.Params()); typ != nil {
+				return typ
+			}
+			if typ := o.uses64Bit(sig.Results()); typ != nil {
+				return typ
+			}
+		}
+	}
+	return nil
+}
+
+// Class returns the S3 class of R values of typ, or the empty string if
+// they are not given a class. The class is named by the class struct tag
+// option of a blank field of a struct type, for example
+//
+//	_ struct{} `rgo:"wordstats,class"`
+//
+// or by o.ClassNames, or is the package-qualified type name if o.Classes
+// is true. Only named types other than those with a mapping defined by
+// rgo, such as time and enum-like types, are given a class.
+func (o Options) Class(typ types.Type) string {
+	named, ok := typ.(*types.Named)
+	if !ok || IsError(named) || Matrix(named) != NotMatrix || Nullable(named) != nil ||
+		o.Temporal(named) != NotTemporal || Enum(named) != nil || o.Text(named) != NotText || Big(named) != NotBig ||
+		imageType(named) != NotImage || arrowType(named) != NotArrow || IsSEXP(named) {
+		return ""
+	}
+	switch named.Underlying().(type) {
+	case *types.Interface, *types.Signature:
+		return ""
+	}
+	if s, ok := named.Underlying().(*types.Struct); ok {
+		for i := 0; i < s.NumFields(); i++ {
+			if s.Field(i).Name() != "_" {
+				continue
+			}
+			if name, opts := o.fieldTag(s, i); opts.Contains("class") && name != "" {
+				return name
+			}
+		}
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil {
+		return ""
+	}
+	if class, ok := o.ClassNames[obj.Pkg().Path()+"."+obj.Name()]; ok {
+		return class
+	}
+	if o.Classes {
+		return obj.Pkg().Name() + "." + obj.Name()
+	}
+	return ""
+}
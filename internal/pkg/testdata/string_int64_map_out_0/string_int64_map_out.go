@@ -0,0 +1,9 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package string_int64_map_out_0
+
+//{"out":["int64","map[string]int64","string"]}
+func Test0() map[string]int64 {
+	var res0 map[string]int64
+	return res0
+}
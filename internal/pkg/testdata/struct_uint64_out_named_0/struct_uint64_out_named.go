@@ -0,0 +1,11 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package struct_uint64_out_named_0
+
+//{"out":["struct{F1 uint64; F2 uint64 \"rgo:\\\"Rname\\\"\"}","uint64"]}
+func Test0() (res0 struct {
+	F1 uint64
+	F2 uint64 "rgo:\"Rname\""
+}) {
+	return res0
+}
@@ -36,6 +36,13 @@ var crafted = []pkg{
 			{In: []string{"T", "S1"}, HelpIn: []string{"int", "string"}, Out: []string{"S1"}, HelpOut: []string{"string"}},
 		},
 	},
+	{
+		Name: "matrix",
+		Path: "github.com/rgonomic/rgo/internal/pkg/testdata",
+		Funcs: []fn{
+			{In: []string{"[][]float64"}, Out: []string{"[][]int32"}},
+		},
+	},
 }
 
 type pkg struct {
@@ -56,15 +63,8 @@ type fn struct {
 }
 
 func builtins() []pkg {
-	skip := map[types.BasicKind]bool{
-		types.Uint64: true,
-		types.Int64:  true,
-	}
 	var pkgs []pkg
 	for t := types.Bool; t <= types.String; t++ {
-		if skip[t] {
-			continue
-		}
 		pkgs = addTypeTest(pkgs, types.Typ[t])
 	}
 	pkgs = addTypeTest(pkgs, types.Universe.Lookup("byte").Type().(*types.Basic))
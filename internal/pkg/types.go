@@ -8,12 +8,17 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/token"
 	"go/types"
 	"log"
+	"math"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -22,35 +27,371 @@ import (
 type Info struct {
 	Funcs []FuncInfo
 
+	// Consts holds the exported constants that
+	// are defined as R objects.
+	Consts []ConstInfo
+
 	Unpackers unpackers
 	Packers   packers
+
+	// Options holds the type mapping options used
+	// for the analysis.
+	Options Options
+}
+
+// NeedHandles returns whether any wrapped function uses external pointer
+// handles.
+func (p *Info) NeedHandles() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if p.Options.IsHandle(typ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedFrames returns whether any wrapped function returns data.frame
+// values.
+func (p *Info) NeedFrames() bool {
+	for _, typ := range p.Packers {
+		if p.Options.Frame(typ) != NotFrame {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedClasses returns whether any wrapped function uses values of named
+// types that are given an S3 class, including sealed interface types.
+func (p *Info) NeedClasses() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if p.Options.Class(typ) != "" || Union(typ) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedDynamic returns whether any wrapped function uses values of empty
+// interface types.
+func (p *Info) NeedDynamic() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if IsDynamic(typ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedCallbacks returns whether any wrapped function takes an R function
+// as a Go func value.
+func (p *Info) NeedCallbacks() bool {
+	for _, typ := range p.Unpackers {
+		if _, ok := typ.Underlying().(*types.Signature); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Enums returns the enum-like types used by the wrapped functions.
+func (p *Info) Enums() []types.Type {
+	seen := make(map[string]types.Type)
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for name, typ := range pack {
+			if Enum(typ) != nil {
+				seen[name] = typ
+			}
+		}
+	}
+	return packers(seen).Types()
+}
+
+// NeedTime returns whether any wrapped function uses time or date
+// values.
+func (p *Info) NeedTime() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if p.Options.Temporal(typ) != NotTemporal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DateType returns the date-only type used by the wrapped functions, or
+// nil if no date-only type is used.
+func (p *Info) DateType() types.Type {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if p.Options.Temporal(typ) == Date {
+				return typ
+			}
+		}
+	}
+	return nil
+}
+
+// NeedBig returns whether any wrapped function uses math/big numbers.
+func (p *Info) NeedBig() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if kind, _, _ := BigOf(typ); kind != NotBig {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedImages returns whether any wrapped function uses image types.
+func (p *Info) NeedImages() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if Image(typ) != NotImage {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedArrow returns whether any wrapped function uses Arrow C data
+// interface structs.
+func (p *Info) NeedArrow() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if Arrow(typ) != NotArrow {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedArrays returns whether any wrapped function uses nested arrays
+// exchanged as R arrays.
+func (p *Info) NeedArrays() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if dims, _ := ArrayDims(typ); dims != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedInt64 returns whether any wrapped function uses 64-bit integers,
+// including in dynamically typed values when an Int64 representation is
+// set.
+func (p *Info) NeedInt64() bool {
+	for _, pack := range []map[string]types.Type{p.Unpackers, p.Packers} {
+		for _, typ := range pack {
+			if Is64Bit(typ) || (IsDynamic(typ) && p.Options.Int64 != "") {
+				return true
+			}
+			if s, ok := typ.Underlying().(*types.Slice); ok && Is64Bit(s.Elem()) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (p *Info) Pkg() *types.Package {
-	if len(p.Funcs) == 0 {
-		return nil
+	switch {
+	case len(p.Funcs) != 0:
+		return p.Funcs[0].Pkg()
+	case len(p.Consts) != 0:
+		return p.Consts[0].Pkg()
 	}
-	return p.Funcs[0].Pkg()
+	return nil
+}
+
+// ConstInfo holds type and syntax information about an exported constant
+// that is defined as an R object.
+type ConstInfo struct {
+	*types.Const
+
+	// Doc is the documentation of the constant.
+	Doc *ast.CommentGroup
 }
 
 // FuncInfo holds type and syntax information about a function.
 type FuncInfo struct {
 	*types.Func
 	*ast.FuncDecl
+
+	// TypeArgs holds the type arguments of an
+	// instantiation of a generic function.
+	TypeArgs []types.Type
+
+	// inst is the signature of the instantiated
+	// function if TypeArgs is not empty.
+	inst *types.Signature
+
+	// Object is the package-level constant or
+	// variable accessed by the function if it is
+	// an accessor rather than a declared function.
+	// Accessors with a parameter set the value of
+	// a variable and others return the value.
+	Object types.Object
+
+	// opts holds the type mapping options used
+	// for the analysis.
+	opts Options
 }
 
 func (f FuncInfo) Signature() *types.Signature {
+	if f.inst != nil {
+		return f.inst
+	}
 	return f.Func.Type().(*types.Signature)
 }
 
-func Analyse(path, allowed string, verbose bool) (*Info, error) {
+// Receiver returns the receiver of a method as it is passed from R, or
+// nil if f is not a method. Value receivers of types that are held in R
+// as handles are passed as pointers and blank receivers are named recv.
+func (f FuncInfo) Receiver() *types.Var {
+	recv := f.Signature().Recv()
+	if recv == nil {
+		return nil
+	}
+	typ := recv.Type()
+	if _, ok := typ.(*types.Pointer); !ok && f.opts.IsHandle(types.NewPointer(typ)) {
+		typ = types.NewPointer(typ)
+	}
+	name := recv.Name()
+	if name == "" || name == "_" {
+		name = "recv"
+	}
+	return types.NewParam(recv.Pos(), recv.Pkg(), name, typ)
+}
+
+// Params returns the parameters of the function as they are passed from R.
+// For methods the receiver is the first parameter.
+func (f FuncInfo) Params() []*types.Var {
+	par := f.Signature().Params()
+	var vars []*types.Var
+	if recv := f.Receiver(); recv != nil {
+		vars = append(vars, recv)
+	}
+	for i := 0; i < par.Len(); i++ {
+		vars = append(vars, par.At(i))
+	}
+	return vars
+}
+
+// Variadic returns the variadic parameter of the function, or nil if the
+// function is not variadic. Variadic parameters are passed from R as the
+// elements of the R ... argument.
+func (f FuncInfo) Variadic() *types.Var {
+	sig := f.Signature()
+	if !sig.Variadic() {
+		return nil
+	}
+	par := sig.Params()
+	return par.At(par.Len() - 1)
+}
+
+// QualifiedName returns the name of the function, qualified by the name
+// of its receiver's type if it is a method.
+func (f FuncInfo) QualifiedName() string {
+	if f.Object != nil {
+		return f.Object.Name()
+	}
+	recv := f.Signature().Recv()
+	if recv == nil {
+		return f.Func.Name()
+	}
+	return recvTypeName(recv.Type()).Obj().Name() + "." + f.Func.Name()
+}
+
+// Ident returns an identifier for the function that is unique within
+// the package. It is the qualified name with the dot replaced by an
+// underscore, followed by the type arguments of an instantiated generic
+// function spelled as identifiers. The identifier of an accessor is its
+// function name.
+func (f FuncInfo) Ident() string {
+	if f.Object != nil {
+		return f.Func.Name()
+	}
+	ident := strings.Replace(f.QualifiedName(), ".", "_", 1)
+	for _, typ := range f.TypeArgs {
+		ident += "_" + typeIdent(typ)
+	}
+	return ident
+}
+
+// InstanceName returns the qualified name of the function followed by
+// its type arguments if it is an instantiated generic function.
+func (f FuncInfo) InstanceName() string {
+	if len(f.TypeArgs) == 0 {
+		return f.QualifiedName()
+	}
+	args := make([]string, len(f.TypeArgs))
+	for i, typ := range f.TypeArgs {
+		args[i] = types.TypeString(typ, func(pkg *types.Package) string {
+			return pkg.Name()
+		})
+	}
+	return fmt.Sprintf("%s[%s]", f.QualifiedName(), strings.Join(args, ", "))
+}
+
+// typeIdent returns typ spelled as an identifier fragment, with composite
+// type constructors written as words and named types qualified by their
+// package name.
+func typeIdent(typ types.Type) string {
+	s := types.TypeString(typ, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+	s = strings.NewReplacer("[]", "slice_", "*", "ptr_", "map[", "map_").Replace(s)
+	var buf strings.Builder
+	sep := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if sep && buf.Len() != 0 {
+				buf.WriteByte('_')
+			}
+			buf.WriteRune(r)
+			sep = false
+		} else {
+			sep = true
+		}
+	}
+	return buf.String()
+}
+
+// recvTypeName returns the named type of a method receiver.
+func recvTypeName(typ types.Type) *types.Named {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	return typ.(*types.Named)
+}
+
+func Analyse(path, allowed string, opts Options, verbose bool) (*Info, error) {
 	if strings.HasSuffix(path, "...") {
 		return nil, errors.New("pkg: invalid use of ... suffix")
 	}
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
 
 	cfg := &packages.Config{
 		Mode: packages.NeedFiles |
 			packages.NeedSyntax |
+			packages.NeedImports |
+			packages.NeedDeps |
 			packages.NeedTypes |
 			packages.NeedTypesInfo,
 	}
@@ -84,59 +425,185 @@ func Analyse(path, allowed string, verbose bool) (*Info, error) {
 	var funcs []FuncInfo
 	needUnpack := make(unpackers)
 	needPack := make(packers)
+
+	// wrap adds info to the wrapped functions if its parameters
+	// and results can be exchanged with R.
+	wrap := func(info FuncInfo) {
+		name := info.InstanceName()
+		par := types.NewTuple(info.Params()...)
+		err := opts.checkType(par, par, true)
+		if err != nil {
+			if verbose {
+				log.Printf("skipping %s: %v", name, err)
+			}
+			return
+		}
+		res := info.Signature().Results()
+		err = opts.checkType(res, res, false)
+		if err != nil {
+			if verbose {
+				log.Printf("skipping %s: %v", name, err)
+			}
+			return
+		}
+		if opts.Int64 == "" {
+			typ := opts.uses64Bit(par)
+			if typ == nil {
+				typ = opts.uses64Bit(res)
+			}
+			if typ != nil {
+				if verbose {
+					log.Printf("skipping %s: unhandled integer type %s without int64 representation", name, typ)
+				}
+				return
+			}
+		}
+		funcs = append(funcs, info)
+
+		if v := info.Variadic(); v != nil {
+			// Variadic parameters are unpacked element-wise.
+			params := info.Params()
+			par = types.NewTuple(params[:len(params)-1]...)
+			elem := v.Type().(*types.Slice).Elem()
+			opts.walk(needUnpack, elem, elem)
+		}
+		opts.walk(needUnpack, par, par)
+		opts.walk(needPack, res, res)
+	}
+	var values []*ast.GenDecl
 	for _, f := range pkg.Syntax {
 		for _, decl := range f.Decls {
 			fd, ok := decl.(*ast.FuncDecl)
 			if !ok {
+				if gd, ok := decl.(*ast.GenDecl); ok && (gd.Tok == token.CONST || gd.Tok == token.VAR) {
+					values = append(values, gd)
+				}
 				continue
 			}
 
 			fn := pkg.TypesInfo.Defs[fd.Name].(*types.Func)
+			info := FuncInfo{
+				Func:     fn,
+				FuncDecl: fd,
+				opts:     opts,
+			}
+			name := info.QualifiedName()
 			if !fn.Exported() {
 				if verbose {
-					log.Printf("skipping %s: unexported function", fn.Name())
+					log.Printf("skipping %s: unexported function", name)
 				}
 				continue
 			}
-			if !allow.MatchString(fn.Name()) {
+			sig := fn.Type().(*types.Signature)
+			if sig.Recv() != nil && !recvTypeName(sig.Recv().Type()).Obj().Exported() {
 				if verbose {
-					log.Printf("skipping %s: not allowed name", fn.Name())
+					log.Printf("skipping %s: unexported receiver type", name)
 				}
 				continue
 			}
-			sig := fn.Type().(*types.Signature)
-			if sig.Recv() != nil {
+			if !allow.MatchString(name) {
 				if verbose {
-					log.Printf("skipping %s.%s: method function", sig.Recv().Type(), fn.Name())
+					log.Printf("skipping %s: not allowed name", name)
 				}
 				continue
 			}
-
-			par := sig.Params()
-			err := checkType(par, par, true)
-			if err != nil {
+			if sig.RecvTypeParams().Len() != 0 {
 				if verbose {
-					log.Printf("skipping %s: %v", fn.Name(), err)
+					log.Printf("skipping %s: generic receiver type", name)
 				}
 				continue
 			}
-			res := sig.Results()
-			err = checkType(res, res, false)
-			if err != nil {
-				if verbose {
-					log.Printf("skipping %s: %v", fn.Name(), err)
+			insts := []FuncInfo{info}
+			if sig.TypeParams().Len() != 0 {
+				insts, err = instantiate(pkg, f, info)
+				if err != nil {
+					return nil, err
+				}
+				if len(insts) == 0 {
+					if verbose {
+						log.Printf("skipping %s: no type arguments for generic function", name)
+					}
+					continue
 				}
-				continue
 			}
-			funcs = append(funcs, FuncInfo{
-				Func:     fn,
-				FuncDecl: fd,
-			})
+			for _, info := range insts {
+				wrap(info)
+			}
+		}
+	}
 
-			walk(needUnpack, par, par)
-			walk(needPack, res, res)
+	// Exported constants that can be written exactly as R
+	// literals are defined as R objects. Other constants are
+	// read, and variables read and set, by accessor functions.
+	declared := make(map[string]bool)
+	for _, fn := range funcs {
+		declared[fn.Ident()] = true
+	}
+	var consts []ConstInfo
+	for _, gd := range values {
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			doc := vs.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			for _, id := range vs.Names {
+				obj := pkg.TypesInfo.Defs[id]
+				if obj == nil || !obj.Exported() {
+					continue
+				}
+				name := obj.Name()
+				if !allow.MatchString(name) {
+					if verbose {
+						log.Printf("skipping %s: not allowed name", name)
+					}
+					continue
+				}
+				if c, ok := obj.(*types.Const); ok && isLiteral(c) {
+					consts = append(consts, ConstInfo{Const: c, Doc: doc})
+					continue
+				}
+				if c, ok := obj.(*types.Const); ok && constType(c) == nil {
+					if verbose {
+						log.Printf("skipping %s: value overflows %s", name, types.Default(c.Type()))
+					}
+					continue
+				}
+				if named, ok := obj.Type().(*types.Named); ok && !named.Obj().Exported() {
+					if verbose {
+						log.Printf("skipping %s: unexported type %s", name, named)
+					}
+					continue
+				}
+				for _, info := range accessors(obj, doc, opts) {
+					if declared[info.Ident()] {
+						if verbose {
+							log.Printf("skipping %s accessor: name collision with %s", name, info.Ident())
+						}
+						continue
+					}
+					wrap(info)
+				}
+			}
 		}
+	}
 
+	// Arguments to R functions called from Go are packed
+	// and the results of the calls are unpacked.
+	for _, typ := range needUnpack.Types() {
+		sig, ok := typ.Underlying().(*types.Signature)
+		if !ok {
+			continue
+		}
+		par := sig.Params()
+		opts.walk(needPack, par, par)
+		res := sig.Results()
+		for i := 0; i < res.Len(); i++ {
+			typ := res.At(i).Type()
+			if !IsError(typ) {
+				opts.walk(needUnpack, typ, typ)
+			}
+		}
 	}
 
 	// Check for mangled name collisions.
@@ -153,30 +620,211 @@ func Analyse(path, allowed string, verbose bool) (*Info, error) {
 		}
 	}
 
-	return &Info{Funcs: funcs, Unpackers: needUnpack, Packers: needPack}, nil
+	return &Info{Funcs: funcs, Consts: consts, Unpackers: needUnpack, Packers: needPack, Options: opts}, nil
+}
+
+// isLiteral returns whether the value of c can be written exactly as an R
+// literal of the type values of its Go type are exchanged as. Constants of
+// named types are not written as literals so that they keep the R
+// representation of their type.
+func isLiteral(c *types.Const) bool {
+	typ, ok := types.Default(c.Type()).(*types.Basic)
+	if !ok {
+		return false
+	}
+	val := c.Val()
+	switch info := typ.Info(); {
+	case info&types.IsBoolean != 0:
+		return true
+	case info&types.IsString != 0:
+		s := constant.StringVal(val)
+		return utf8.ValidString(s) && !strings.ContainsRune(s, 0)
+	case info&types.IsInteger != 0:
+		v, exact := constant.Int64Val(val)
+		return exact && math.MinInt32 < v && v <= math.MaxInt32
+	case info&types.IsFloat != 0:
+		v, _ := constant.Float64Val(val)
+		return !math.IsInf(v, 0)
+	case info&types.IsComplex != 0:
+		re, _ := constant.Float64Val(constant.Real(val))
+		im, _ := constant.Float64Val(constant.Imag(val))
+		return !math.IsInf(re, 0) && !math.IsInf(im, 0)
+	}
+	return false
+}
+
+// constType returns the type that the value of the constant c is read
+// as by its accessor, or nil if the value overflows the default type of
+// c. Untyped integer constants outside the range of R integers are read
+// as int64 so that they are exchanged according to the Int64 option.
+func constType(c *types.Const) types.Type {
+	typ := types.Default(c.Type())
+	basic, ok := c.Type().(*types.Basic)
+	if !ok || basic.Info()&types.IsUntyped == 0 {
+		// Typed constants are representable by their type.
+		return typ
+	}
+	val := c.Val()
+	switch basic.Kind() {
+	case types.UntypedInt:
+		v, exact := constant.Int64Val(val)
+		switch {
+		case !exact:
+			return nil
+		case v <= math.MinInt32 || math.MaxInt32 < v:
+			return types.Typ[types.Int64]
+		}
+	case types.UntypedRune:
+		v, exact := constant.Int64Val(val)
+		if !exact || v < math.MinInt32 || math.MaxInt32 < v {
+			return nil
+		}
+	case types.UntypedFloat:
+		if v, _ := constant.Float64Val(val); math.IsInf(v, 0) {
+			return nil
+		}
+	case types.UntypedComplex:
+		re, _ := constant.Float64Val(constant.Real(val))
+		im, _ := constant.Float64Val(constant.Imag(val))
+		if math.IsInf(re, 0) || math.IsInf(im, 0) {
+			return nil
+		}
+	}
+	return typ
+}
+
+// accessors returns the functions returning the value of the exported
+// package-level constant or variable obj, and setting the value of a
+// variable. Constants are read by a function with the name of the
+// constant and variables by functions named with a Get or Set prefix.
+func accessors(obj types.Object, doc *ast.CommentGroup, opts Options) []FuncInfo {
+	if c, ok := obj.(*types.Const); ok {
+		res := types.NewTuple(types.NewVar(obj.Pos(), obj.Pkg(), "", constType(c)))
+		return []FuncInfo{accessor(obj, obj.Name(), nil, res, doc, opts)}
+	}
+	typ := types.Default(obj.Type())
+	res := types.NewTuple(types.NewVar(obj.Pos(), obj.Pkg(), "", typ))
+	par := types.NewTuple(types.NewParam(obj.Pos(), obj.Pkg(), "value", typ))
+	return []FuncInfo{
+		accessor(obj, "Get"+obj.Name(), nil, res, doc, opts),
+		accessor(obj, "Set"+obj.Name(), par, nil, doc, opts),
+	}
+}
+
+// accessor returns an accessor function for obj with the given name,
+// parameters and results.
+func accessor(obj types.Object, name string, par, res *types.Tuple, doc *ast.CommentGroup, opts Options) FuncInfo {
+	sig := types.NewSignatureType(nil, nil, nil, par, res, false)
+	return FuncInfo{
+		Func:     types.NewFunc(obj.Pos(), obj.Pkg(), name, sig),
+		FuncDecl: &ast.FuncDecl{Doc: doc, Name: ast.NewIdent(name)},
+		Object:   obj,
+		opts:     opts,
+	}
+}
+
+// instantiate returns the instantiations of the generic function described
+// by info for each list of type arguments given for it in the analysis
+// options. The type arguments are evaluated in the scope of f, the file
+// holding the function's declaration.
+func instantiate(pkg *packages.Package, f *ast.File, info FuncInfo) ([]FuncInfo, error) {
+	name := info.QualifiedName()
+	var insts []FuncInfo
+	seen := make(map[string]bool)
+	for _, list := range info.opts.TypeArgs[name] {
+		args := make([]types.Type, len(list))
+		for i, expr := range list {
+			tv, err := types.Eval(pkg.Fset, pkg.Types, f.Name.Pos(), expr)
+			if err != nil {
+				return nil, fmt.Errorf("pkg: invalid type argument for %s: %w", name, err)
+			}
+			if !tv.IsType() {
+				return nil, fmt.Errorf("pkg: invalid type argument for %s: %s is not a type", name, expr)
+			}
+			args[i] = tv.Type
+		}
+		typ, err := types.Instantiate(nil, info.Func.Type(), args, true)
+		if err != nil {
+			return nil, fmt.Errorf("pkg: cannot instantiate %s: %w", name, err)
+		}
+		inst := info
+		inst.TypeArgs = args
+		inst.inst = typ.(*types.Signature)
+		if seen[inst.Ident()] {
+			return nil, fmt.Errorf("pkg: duplicate instantiation of %s", inst.InstanceName())
+		}
+		seen[inst.Ident()] = true
+		insts = append(insts, inst)
+	}
+	return insts, nil
+}
+
+// checkType returns an error if typ cannot be exchanged with R.
+func (o Options) checkType(typ, named types.Type, parameters bool) error {
+	return o.checkTypeSeen(typ, named, parameters, make(checked))
 }
 
-// TODO(kortschak): Handle recursive type definitions correctly.
+// checked holds the results of checking named types in each direction.
+type checked map[checkedType]error
+
+// checkedType is a named type checked for exchange with R in the direction
+// given by parameters.
+type checkedType struct {
+	typ        *types.Named
+	parameters bool
+}
 
-func checkType(typ, named types.Type, parameters bool) error {
+// checkTypeSeen returns an error if typ cannot be exchanged with R. The
+// result of checking each named type in each direction is recorded in
+// seen so that recursive type definitions terminate; recursive references
+// to a named type that is being checked are assumed to be valid.
+func (o Options) checkTypeSeen(typ, named types.Type, parameters bool, seen checked) error {
+	if Image(typ) != NotImage {
+		// Images are converted by helpers.
+		return nil
+	}
+	if Arrow(typ) != NotArrow {
+		// Arrow structs are exchanged by reference.
+		return nil
+	}
+	if IsSEXP(typ) {
+		// R values are passed through.
+		return nil
+	}
 	switch typ := typ.(type) {
 	case *types.Named:
-		return checkType(typ.Underlying(), typ, parameters)
+		if Matrix(typ) != NotMatrix || Nullable(typ) != nil || Temporal(typ) != NotTemporal {
+			// Gonum matrix, database/sql nullable and
+			// time types are handled specially.
+			return nil
+		}
+		if Big(typ) != NotBig {
+			return nil
+		}
+		if kind := o.Text(typ); kind != NotText {
+			if kind == Stringer && parameters {
+				return fmt.Errorf("unhandled output-only fmt.Stringer type %s", typ)
+			}
+			return nil
+		}
+		key := checkedType{typ: typ, parameters: parameters}
+		if err, ok := seen[key]; ok {
+			return err
+		}
+		seen[key] = nil
+		err := o.checkTypeSeen(typ.Underlying(), typ, parameters, seen)
+		seen[key] = err
+		return err
 
 	case *types.Array:
 		elem := typ.Elem()
-		return checkType(elem, elem, parameters)
+		return o.checkTypeSeen(elem, elem, parameters, seen)
 
 	case *types.Basic:
 		switch kind := typ.Kind(); kind {
 		case types.Uint8, types.Int32:
 			// Dealias rune and byte.
 			*typ = *types.Typ[kind]
-		case types.Int64, types.Uint64:
-			if typ == named {
-				return fmt.Errorf("unhandled integer type %s", typ)
-			}
-			return fmt.Errorf("unhandled integer type %s (%s)", named, typ)
 		}
 
 	case *types.Chan:
@@ -186,26 +834,47 @@ func checkType(typ, named types.Type, parameters bool) error {
 		return fmt.Errorf("unhandled chan type %s (%s)", named, typ)
 
 	case *types.Interface:
-		if !IsError(named) {
+		if IsError(named) || typ.Empty() {
+			return nil
+		}
+		impls := Union(named)
+		if impls == nil {
 			return fmt.Errorf("unhandled interface type %s", named)
 		}
+		for _, typ := range impls {
+			err := o.checkTypeSeen(typ, typ, parameters, seen)
+			if err != nil {
+				return err
+			}
+		}
 
 	case *types.Map:
-		if !types.Identical(typ.Key().Underlying(), types.Typ[types.String]) {
+		if Map(typ) == NotMap {
 			if typ == named {
-				return fmt.Errorf("unhandled non-string keyed map type %s", typ)
+				return fmt.Errorf("unhandled non-basic keyed map type %s", typ)
 			}
-			return fmt.Errorf("unhandled non-string keyed map type %s (%s)", named, typ)
+			return fmt.Errorf("unhandled non-basic keyed map type %s (%s)", named, typ)
+		}
+		key := typ.Key()
+		err := o.checkTypeSeen(key, key, parameters, seen)
+		if err != nil {
+			return err
+		}
+		if Map(typ) == SetMap {
+			break
 		}
 		elem := typ.Elem()
-		err := checkType(elem, elem, parameters)
+		err = o.checkTypeSeen(elem, elem, parameters, seen)
 		if err != nil {
 			return err
 		}
 
 	case *types.Pointer:
+		if o.isHandle(typ, seen) {
+			return nil
+		}
 		elem := typ.Elem()
-		err := checkType(elem, elem, parameters)
+		err := o.checkTypeSeen(elem, elem, parameters, seen)
 		if err != nil {
 			return err
 		}
@@ -219,14 +888,43 @@ func checkType(typ, named types.Type, parameters bool) error {
 		}
 
 	case *types.Signature:
-		if typ == named {
-			return fmt.Errorf("unhandled function type with signature %s", typ)
+		// Only parameters may be functions. These are R
+		// functions that are called from Go.
+		if !parameters {
+			if typ == named {
+				return fmt.Errorf("unhandled function type with signature %s", typ)
+			}
+			return fmt.Errorf("unhandled function type with signature %s (%s)", named, typ)
+		}
+		if typ.Variadic() {
+			return fmt.Errorf("unhandled variadic function parameter type %s", named)
+		}
+		res := typ.Results()
+		n := res.Len()
+		if n != 0 && IsError(res.At(n-1).Type()) {
+			n--
+		}
+		if n > 1 {
+			return fmt.Errorf("unhandled multiple result function parameter type %s", named)
+		}
+		// Callback arguments are packed for R and
+		// results are unpacked from R.
+		par := typ.Params()
+		err := o.checkTypeSeen(par, par, false, seen)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			typ := res.At(i).Type()
+			err := o.checkTypeSeen(typ, typ, true, seen)
+			if err != nil {
+				return err
+			}
 		}
-		return fmt.Errorf("unhandled function type with signature %s (%s)", named, typ)
 
 	case *types.Slice:
 		elem := typ.Elem()
-		err := checkType(elem, elem, parameters)
+		err := o.checkTypeSeen(elem, elem, parameters, seen)
 		if err != nil {
 			return err
 		}
@@ -240,9 +938,18 @@ func checkType(typ, named types.Type, parameters bool) error {
 		}
 
 	case *types.Struct:
-		for i := 0; i < typ.NumFields(); i++ {
-			f := typ.Field(i).Type()
-			err := checkType(f, f, parameters)
+		if typ == named && o.SkipsFields(typ) {
+			return fmt.Errorf("unhandled skipped field in unnamed struct type %s", typ)
+		}
+		for _, f := range o.Fields(typ) {
+			if !f.Var.Exported() {
+				if typ == named {
+					return fmt.Errorf("unhandled unexported field %s in %s", f.Var.Name(), typ)
+				}
+				return fmt.Errorf("unhandled unexported field %s in %s (%s)", f.Var.Name(), named, typ)
+			}
+			f := f.Var.Type()
+			err := o.checkTypeSeen(f, f, parameters, seen)
 			if err != nil {
 				return err
 			}
@@ -255,7 +962,7 @@ func checkType(typ, named types.Type, parameters bool) error {
 				return fmt.Errorf("unhandled function with blank parameter name %q", f)
 			}
 			typ := f.Type()
-			err := checkType(typ, typ, parameters)
+			err := o.checkTypeSeen(typ, typ, parameters, seen)
 			if err != nil {
 				return err
 			}
@@ -268,7 +975,7 @@ func checkType(typ, named types.Type, parameters bool) error {
 type unpackers map[string]types.Type
 
 func (v unpackers) visit(typ types.Type) {
-	if _, ok := typ.Underlying().(*types.Interface); ok {
+	if _, ok := typ.Underlying().(*types.Interface); ok && Union(typ) == nil && !IsDynamic(typ) && Image(typ) == NotImage {
 		panic(fmt.Sprintf("unhandled input parameter type: %q", typ))
 	}
 	s := typ.String()
@@ -280,6 +987,11 @@ func (v unpackers) visit(typ types.Type) {
 	}
 }
 
+func (v unpackers) visited(typ types.Type) bool {
+	_, ok := v[typ.String()]
+	return ok
+}
+
 func (v unpackers) also(typ types.Type) types.BasicKind {
 	if typ, ok := typ.(*types.Basic); ok {
 		// Make sure we have a complex128 value we can convert to complex64.
@@ -308,7 +1020,12 @@ func (v packers) visit(typ types.Type) {
 	}
 }
 
-func (v packers) NeedList() bool {
+func (v packers) visited(typ types.Type) bool {
+	_, ok := v[typ.String()]
+	return ok
+}
+
+func (v packers) NeedList() bool {
 	for _, typ := range v {
 		switch typ.(type) {
 		case *types.Struct, *types.Map:
@@ -335,13 +1052,72 @@ func (t byName) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 
 type visitor interface {
 	visit(typ types.Type)
+	visited(typ types.Type) bool
 }
 
-func walk(v visitor, typ, named types.Type) {
+func (o Options) walk(v visitor, typ, named types.Type) {
+	if Matrix(typ) != NotMatrix {
+		v.visit(typ)
+		return
+	}
+	if dims, elem := ArrayDims(typ); dims != nil {
+		// Nested arrays are packed and unpacked as
+		// flat slices with a dim attribute.
+		v.visit(typ)
+		v.visit(types.NewSlice(elem))
+		return
+	}
+	if elem := Nullable(typ); elem != nil {
+		v.visit(typ)
+		v.visit(elem)
+		return
+	}
+	if Temporal(typ) != NotTemporal {
+		v.visit(typ)
+		return
+	}
+	if Enum(typ) != nil {
+		// Enum values are packed and unpacked as factor vectors.
+		v.visit(typ)
+		v.visit(types.NewSlice(typ))
+		return
+	}
+	if Image(typ) != NotImage {
+		// Images are packed and unpacked by helpers
+		// for all image types.
+		v.visit(typ)
+		return
+	}
+	if Arrow(typ) != NotArrow || IsSEXP(typ) {
+		v.visit(typ)
+		return
+	}
+	if kind, _, _ := BigOf(typ); kind != NotBig {
+		// Numbers are packed and unpacked by helpers
+		// for each math/big type.
+		v.visit(typ)
+		return
+	}
+	if o.Text(typ) != NotText {
+		// Text values are packed and unpacked as strings.
+		v.visit(typ)
+		v.visit(types.Typ[types.String])
+		return
+	}
+	if s, ok := typ.(*types.Slice); ok && o.Text(s.Elem()) != NotText {
+		v.visit(typ)
+		v.visit(types.NewSlice(types.Typ[types.String]))
+		return
+	}
 	switch typ := typ.(type) {
 	case *types.Named:
+		if v.visited(typ) {
+			// Named types are walked once so that recursive
+			// type definitions terminate.
+			return
+		}
 		v.visit(typ)
-		walk(v, typ.Underlying(), typ)
+		o.walk(v, typ.Underlying(), typ)
 
 	case *types.Array:
 		elem := typ.Elem()
@@ -349,13 +1125,6 @@ func walk(v visitor, typ, named types.Type) {
 		v.visit(types.NewSlice(elem)) // This will visit the element in the slice walk.
 
 	case *types.Basic:
-		switch typ.Kind() {
-		case types.Int64, types.Uint64:
-			if typ == named {
-				panic(fmt.Sprintf("unhandled integer type %s", typ))
-			}
-			panic(fmt.Sprintf("unhandled integer type %s (%s)", named, typ))
-		}
 		v.visit(typ)
 
 	case *types.Chan:
@@ -365,6 +1134,16 @@ func walk(v visitor, typ, named types.Type) {
 		panic(fmt.Sprintf("unhandled chan type %s (%s)", named, typ))
 
 	case *types.Interface:
+		if typ.Empty() {
+			v.visit(named)
+			return
+		}
+		if impls := Union(named); impls != nil {
+			for _, typ := range impls {
+				o.walk(v, typ, typ)
+			}
+			return
+		}
 		if !IsError(named) {
 			panic(fmt.Sprintf("unhandled interface type %s", named))
 		}
@@ -372,55 +1151,1038 @@ func walk(v visitor, typ, named types.Type) {
 		v.visit(named)
 
 	case *types.Map:
-		if !types.Identical(typ.Key().Underlying(), types.Typ[types.String]) {
+		v.visit(typ)
+		switch Map(typ) {
+		case NamedMap:
+			key := typ.Key()
+			o.walk(v, key, key)
+			elem := typ.Elem()
+			o.walk(v, elem, elem)
+			if !types.Identical(key, types.Typ[types.String]) {
+				// Named string keys are converted via a string keyed map.
+				m := types.NewMap(types.Typ[types.String], elem)
+				o.walk(v, m, m)
+			}
+		case KeyedMap:
+			keys := o.MapSlice(typ.Key())
+			o.walk(v, keys, keys)
+			values := o.MapSlice(typ.Elem())
+			o.walk(v, values, values)
+		case SetMap:
+			keys := o.MapSlice(typ.Key())
+			o.walk(v, keys, keys)
+		default:
 			if typ == named {
-				panic(fmt.Sprintf("unhandled non-string keyed map type %s", typ))
+				panic(fmt.Sprintf("unhandled non-basic keyed map type %s", typ))
 			}
-			panic(fmt.Sprintf("unhandled non-string keyed map type %s (%s)", named, typ))
+			panic(fmt.Sprintf("unhandled non-basic keyed map type %s (%s)", named, typ))
 		}
-		key := typ.Key()
-		walk(v, key, key)
-		elem := typ.Elem()
-		v.visit(typ)
-		walk(v, elem, elem)
 
 	case *types.Pointer:
-		elem := typ.Elem()
 		v.visit(typ)
-		walk(v, elem, elem)
+		if o.IsHandle(typ) {
+			return
+		}
+		elem := typ.Elem()
+		o.walk(v, elem, elem)
 
 	case *types.Signature:
-		if typ == named {
-			panic(fmt.Sprintf("unhandled function type %s", typ))
-		}
-		panic(fmt.Sprintf("unhandled function type %s (%s)", named, typ))
+		// The parameters and results of function types are
+		// walked by Analyse.
+		v.visit(typ)
 
 	case *types.Slice:
 		elem := typ.Elem()
 		v.visit(typ)
+		if o.Frame(typ) == RowFrame {
+			for _, col := range o.FrameColumns(typ) {
+				v.visit(col)
+			}
+			return
+		}
 		if _, ok := elem.Underlying().(*types.Basic); !ok {
-			walk(v, elem, elem)
+			o.walk(v, elem, elem)
 		}
 
 	case *types.Struct:
-		for i := 0; i < typ.NumFields(); i++ {
-			f := typ.Field(i).Type()
-			walk(v, f, f)
+		for _, f := range o.Fields(typ) {
+			f := f.Var.Type()
+			o.walk(v, f, f)
+		}
+		if o.SkipsFields(typ) {
+			// Structs with skipped fields are packed and
+			// unpacked by the functions for their named type.
+			return
 		}
 		v.visit(typ)
 
 	case *types.Tuple:
 		for i := 0; i < typ.Len(); i++ {
 			f := typ.At(i).Type()
-			walk(v, f, f)
+			o.walk(v, f, f)
 		}
 	}
 }
 
+// IsHandle returns whether typ is a pointer to a named struct type that
+// cannot be converted to an R value. Values of these types are held in R
+// as external pointer handles.
+func (o Options) IsHandle(typ types.Type) bool {
+	return o.isHandle(typ, make(checked))
+}
+
+// isHandle returns whether typ is a handle type, using the named type
+// check results in seen.
+func (o Options) isHandle(typ types.Type, seen checked) bool {
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || Matrix(named) != NotMatrix || Image(ptr) != NotImage || Arrow(ptr) != NotArrow {
+		return false
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return false
+	}
+	return o.checkTypeSeen(named, named, false, seen) != nil
+}
+
 func IsError(typ types.Type) bool {
 	return types.Identical(typ, types.Universe.Lookup("error").Type())
 }
 
+// SEXPPath is the import path of the rgo package providing typed
+// access to arbitrary R values.
+const SEXPPath = "github.com/rgonomic/rgo/sexp"
+
+// IsSEXP returns whether typ is the sexp.Value type, which is passed
+// through to and from R as an arbitrary R value, SEXP.
+func IsSEXP(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == SEXPPath && obj.Name() == "Value"
+}
+
+// MatrixKind describes the Go memory layout of a type that is
+// exchanged with R as a matrix.
+type MatrixKind int
+
+const (
+	NotMatrix MatrixKind = iota
+
+	// SliceMatrix is a [][]float64 or [][]int32 with
+	// equal length rows.
+	SliceMatrix
+
+	// GeneralMatrix is a gonum blas64.General.
+	GeneralMatrix
+
+	// GeneralColsMatrix is a gonum blas64.GeneralCols.
+	GeneralColsMatrix
+
+	// DenseMatrix is a gonum mat.Dense.
+	DenseMatrix
+)
+
+const (
+	blas64Path = "gonum.org/v1/gonum/blas/blas64"
+	matPath    = "gonum.org/v1/gonum/mat"
+)
+
+// Matrix returns the matrix layout of typ. Named types other than the
+// gonum matrix types are not considered to be matrices; their underlying
+// type may be.
+func Matrix(typ types.Type) MatrixKind {
+	switch typ := typ.(type) {
+	case *types.Named:
+		obj := typ.Obj()
+		if obj.Pkg() == nil {
+			return NotMatrix
+		}
+		switch obj.Pkg().Path() + "." + obj.Name() {
+		case blas64Path + ".General":
+			return GeneralMatrix
+		case blas64Path + ".GeneralCols":
+			return GeneralColsMatrix
+		case matPath + ".Dense":
+			return DenseMatrix
+		}
+	case *types.Slice:
+		row, ok := typ.Elem().(*types.Slice)
+		if !ok {
+			return NotMatrix
+		}
+		elem, ok := row.Elem().(*types.Basic)
+		if !ok {
+			return NotMatrix
+		}
+		switch elem.Kind() {
+		case types.Float64, types.Int32:
+			return SliceMatrix
+		}
+	}
+	return NotMatrix
+}
+
+// ArrayDims returns the lengths of the dimensions of typ, outermost first,
+// and its element type if typ is an unnamed array of arrays, nested to any
+// depth, of an unnamed basic type. Nested arrays are exchanged with R as
+// arrays with a dim attribute. Otherwise ArrayDims returns nil.
+func ArrayDims(typ types.Type) (dims []int64, elem *types.Basic) {
+	for {
+		a, ok := typ.(*types.Array)
+		if !ok {
+			break
+		}
+		dims = append(dims, a.Len())
+		typ = a.Elem()
+	}
+	elem, ok := typ.(*types.Basic)
+	if !ok || len(dims) < 2 {
+		return nil, nil
+	}
+	return dims, elem
+}
+
+// MatrixElem returns the element type of the matrix type typ. It returns
+// nil if typ is not a matrix type.
+func MatrixElem(typ types.Type) *types.Basic {
+	switch Matrix(typ) {
+	case SliceMatrix:
+		return typ.(*types.Slice).Elem().(*types.Slice).Elem().(*types.Basic)
+	case GeneralMatrix, GeneralColsMatrix, DenseMatrix:
+		return types.Typ[types.Float64]
+	}
+	return nil
+}
+
+// Nullable returns the type of the value held by the database/sql
+// nullable type typ, or nil if typ is not one of sql.NullBool,
+// sql.NullFloat64, sql.NullInt32 or sql.NullString. Nullable values are
+// exchanged with R as scalars that may be NA.
+func Nullable(typ types.Type) *types.Basic {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != "database/sql" {
+		return nil
+	}
+	switch obj.Name() {
+	case "NullBool":
+		return types.Typ[types.Bool]
+	case "NullFloat64":
+		return types.Typ[types.Float64]
+	case "NullInt32":
+		return types.Typ[types.Int32]
+	case "NullString":
+		return types.Typ[types.String]
+	}
+	return nil
+}
+
+// Field is a struct field as it is exchanged with R.
+type Field struct {
+	// Var is the Go field.
+	Var *types.Var
+
+	// Name is the name of the R list element
+	// holding the field.
+	Name string
+
+	// Path is the selector path to the field from
+	// the outer struct, for example "Base.ID".
+	Path string
+
+	// OmitEmpty is whether the field is omitted
+	// from packed lists when it is empty.
+	OmitEmpty bool
+
+	// Attr is whether the field is exchanged as
+	// an R attribute rather than a list element.
+	Attr bool
+
+	// Via holds the embedded struct pointer fields
+	// on the path to the field, outermost first.
+	// The field is absent from packed lists when
+	// any of them is nil.
+	Via []Field
+}
+
+// fieldTag returns the name and options of the rgo struct tag of the ith
+// field of s, or of its json struct tag if it has no rgo tag and o.JSONTags
+// is true.
+func (o Options) fieldTag(s *types.Struct, i int) (name string, opts tagOptions) {
+	tag, ok := reflect.StructTag(s.Tag(i)).Lookup("rgo")
+	if !ok && o.JSONTags {
+		tag = reflect.StructTag(s.Tag(i)).Get("json")
+	}
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, ""
+}
+
+// tagOptions is the comma-separated list of options following
+// the name in a struct tag.
+type tagOptions string
+
+// Contains returns whether o contains the option opt.
+func (o tagOptions) Contains(opt string) bool {
+	for _, o := range strings.Split(string(o), ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isSkipped returns whether the ith field of s is skipped by a "-" struct
+// tag or is a blank field. Unexported fields are also skipped when
+// o.JSONTags is true, as they are by encoding/json.
+func (o Options) isSkipped(s *types.Struct, i int) bool {
+	f := s.Field(i)
+	if f.Name() == "_" || (o.JSONTags && !f.Exported()) {
+		return true
+	}
+	name, opts := o.fieldTag(s, i)
+	return name == "-" && opts == ""
+}
+
+// SkipsFields returns whether any field of s, or of the structs that
+// are flattened into s, is skipped by a "-" struct tag or is a blank
+// field.
+func (o Options) SkipsFields(s *types.Struct) bool {
+	return o.skipsFields(s, []*types.Struct{s})
+}
+
+func (o Options) skipsFields(s *types.Struct, outer []*types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if o.isSkipped(s, i) {
+			return true
+		}
+		es := o.flattened(s, i)
+		if es != nil && !isOuter(es, outer) && o.skipsFields(es, append(outer[:len(outer):len(outer)], es)) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattened returns the struct type of the ith field of s if the field
+// is flattened into s, otherwise nil. Exported embedded struct and struct
+// pointer fields without a struct tag name are flattened, as are exported
+// struct and struct pointer fields with the inline struct tag option.
+func (o Options) flattened(s *types.Struct, i int) *types.Struct {
+	f := s.Field(i)
+	if !f.Exported() {
+		return nil
+	}
+	name, opts := o.fieldTag(s, i)
+	if !opts.Contains("inline") && (!f.Embedded() || name != "") {
+		return nil
+	}
+	typ := f.Type().Underlying()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem().Underlying()
+	}
+	es, _ := typ.(*types.Struct)
+	return es
+}
+
+// isOuter returns whether s is in outer, the structs that s would be
+// flattened into. Fields flattened from such a struct would be shadowed,
+// so recursive struct pointers are skipped, as for encoding/json.
+func isOuter(s *types.Struct, outer []*types.Struct) bool {
+	for _, o := range outer {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the fields of s as they are exchanged with R. Fields
+// with a "-" struct tag are skipped. The fields of embedded structs and
+// of structs with the inline struct tag option are flattened into the
+// outer struct; flattened fields are shadowed by shallower fields with
+// the same R name, and fields with the same R name at the same depth
+// are omitted. Fields flattened through struct pointers record the
+// pointers in their Via field.
+func (o Options) Fields(s *types.Struct) []Field {
+	type candidate struct {
+		Field
+		depth int
+	}
+	var cands []candidate
+	var collect func(s *types.Struct, path string, via []Field, outer []*types.Struct)
+	collect = func(s *types.Struct, path string, via []Field, outer []*types.Struct) {
+		for i := 0; i < s.NumFields(); i++ {
+			if o.isSkipped(s, i) {
+				continue
+			}
+			f := s.Field(i)
+			if es := o.flattened(s, i); es != nil {
+				if isOuter(es, outer) {
+					continue
+				}
+				via := via
+				if _, ok := f.Type().Underlying().(*types.Pointer); ok {
+					via = append(via[:len(via):len(via)], Field{Var: f, Name: f.Name(), Path: path + f.Name()})
+				}
+				collect(es, path+f.Name()+".", via, append(outer[:len(outer):len(outer)], es))
+				continue
+			}
+			name, opts := o.fieldTag(s, i)
+			if name == "" {
+				name = f.Name()
+			}
+			cands = append(cands, candidate{
+				Field: Field{
+					Var:       f,
+					Name:      name,
+					Path:      path + f.Name(),
+					OmitEmpty: opts.Contains("omitempty"),
+					Attr:      opts.Contains("attr"),
+					Via:       via,
+				},
+				depth: len(outer) - 1,
+			})
+		}
+	}
+	collect(s, "", nil, []*types.Struct{s})
+
+	// Find the shallowest depth of each R name and
+	// the number of fields with the name at that depth.
+	type dominance struct{ depth, count int }
+	dom := make(map[string]dominance)
+	for _, c := range cands {
+		d, ok := dom[c.Name]
+		switch {
+		case !ok || c.depth < d.depth:
+			dom[c.Name] = dominance{depth: c.depth, count: 1}
+		case c.depth == d.depth:
+			d.count++
+			dom[c.Name] = d
+		}
+	}
+	var fields []Field
+	for _, c := range cands {
+		d := dom[c.Name]
+		if c.depth != d.depth || d.count != 1 {
+			continue
+		}
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// FrameKind describes the Go memory layout of a type that is
+// exchanged with R as a data.frame.
+type FrameKind int
+
+const (
+	NotFrame FrameKind = iota
+
+	// RowFrame is a slice of structs with basic typed
+	// fields. Each element of the slice is a row.
+	RowFrame
+
+	// ColumnFrame is a struct with basic typed slice
+	// fields of equal length and a blank field with the
+	// frame struct tag option. Each field is a column.
+	ColumnFrame
+)
+
+// Payload returns the field of s that is exchanged as the R value of s
+// when all its other fields are exchanged as R attributes, or nil if s is
+// exchanged as a list. A struct has a payload if it has at least one field
+// with the attr struct tag option and exactly one field without, and that
+// field is not flattened through a struct pointer.
+func (o Options) Payload(s *types.Struct) *Field {
+	var (
+		payload *Field
+		attrs   bool
+	)
+	fields := o.Fields(s)
+	for i, f := range fields {
+		if f.Attr {
+			attrs = true
+			continue
+		}
+		if payload != nil {
+			return nil
+		}
+		payload = &fields[i]
+	}
+	if !attrs || payload == nil || payload.Via != nil {
+		return nil
+	}
+	return payload
+}
+
+// Frame returns the data.frame layout of typ. Named types are not
+// considered to be data frames; their underlying type may be.
+func (o Options) Frame(typ types.Type) FrameKind {
+	switch typ := typ.(type) {
+	case *types.Slice:
+		s, ok := typ.Elem().Underlying().(*types.Struct)
+		if ok && o.Text(typ.Elem()) == NotText && o.isFrameStruct(s, false) {
+			return RowFrame
+		}
+	case *types.Struct:
+		if o.isColumnFrame(typ) && o.isFrameStruct(typ, true) {
+			return ColumnFrame
+		}
+	}
+	return NotFrame
+}
+
+// isColumnFrame returns whether s is marked as a column-form data.frame
+// by the frame struct tag option of a blank field, for example
+//
+//	_ struct{} `rgo:",frame"`
+func (o Options) isColumnFrame(s *types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if s.Field(i).Name() != "_" {
+			continue
+		}
+		if _, opts := o.fieldTag(s, i); opts.Contains("frame") {
+			return true
+		}
+	}
+	return false
+}
+
+// isFrameStruct returns whether s has at least one field and all its
+// fields are basic typed, or slices of unnamed basic types if columns
+// is true, and none are flattened through a struct pointer.
+func (o Options) isFrameStruct(s *types.Struct, columns bool) bool {
+	fields := o.Fields(s)
+	if len(fields) == 0 {
+		return false
+	}
+	for _, f := range fields {
+		if f.Attr || f.Via != nil {
+			return false
+		}
+		typ := f.Var.Type().Underlying()
+		if columns {
+			slice, ok := typ.(*types.Slice)
+			if !ok {
+				return false
+			}
+			typ = slice.Elem()
+		}
+		basic, ok := typ.(*types.Basic)
+		if !ok || basic.Kind() == types.UnsafePointer {
+			return false
+		}
+	}
+	return true
+}
+
+// FrameColumns returns the column types of the data.frame type typ. The
+// column types are slices of the underlying basic types of the fields
+// returned by Fields. It returns nil if typ is not a data.frame type.
+func (o Options) FrameColumns(typ types.Type) []*types.Slice {
+	var s *types.Struct
+	switch o.Frame(typ) {
+	case RowFrame:
+		s = typ.(*types.Slice).Elem().Underlying().(*types.Struct)
+	case ColumnFrame:
+		s = typ.(*types.Struct)
+	default:
+		return nil
+	}
+	fields := o.Fields(s)
+	cols := make([]*types.Slice, len(fields))
+	for i, f := range fields {
+		typ := f.Var.Type().Underlying()
+		if slice, ok := typ.(*types.Slice); ok {
+			cols[i] = slice
+			continue
+		}
+		cols[i] = types.NewSlice(typ)
+	}
+	return cols
+}
+
+// MapKind describes how a Go map type is exchanged with R.
+type MapKind int
+
+const (
+	NotMap MapKind = iota
+
+	// NamedMap is a map with string keys. It is exchanged as
+	// a named vector or list.
+	NamedMap
+
+	// KeyedMap is a map with non-string basic keys. It is
+	// exchanged as a list with keys and values elements.
+	KeyedMap
+
+	// SetMap is a map[K]struct{} or a non-string keyed
+	// map[K]bool with basic keys. It is exchanged as an
+	// atomic vector of the keys in the set.
+	SetMap
+)
+
+// Map returns the R representation of the map type typ. Named types are
+// not considered; their underlying type may be. Maps with keys that are
+// not basic types are NotMap.
+func Map(typ types.Type) MapKind {
+	m, ok := typ.(*types.Map)
+	if !ok {
+		return NotMap
+	}
+	key, ok := m.Key().Underlying().(*types.Basic)
+	if !ok {
+		return NotMap
+	}
+	switch elem := m.Elem().Underlying().(type) {
+	case *types.Struct:
+		if elem.NumFields() == 0 {
+			return SetMap
+		}
+	case *types.Basic:
+		if elem.Kind() == types.Bool && key.Kind() != types.String {
+			return SetMap
+		}
+	}
+	if key.Kind() == types.String {
+		return NamedMap
+	}
+	return KeyedMap
+}
+
+// MapSlice returns the slice type used to exchange the keys or values of
+// type typ of a keyed or set map with R. Named basic types without a
+// mapping of their own are exchanged through slices of their underlying
+// type so that they are held in R as atomic vectors.
+func (o Options) MapSlice(typ types.Type) *types.Slice {
+	named, ok := typ.(*types.Named)
+	if !ok || Enum(named) != nil || o.Temporal(named) != NotTemporal || o.Text(named) != NotText {
+		return types.NewSlice(typ)
+	}
+	if basic, ok := named.Underlying().(*types.Basic); ok {
+		return types.NewSlice(basic)
+	}
+	return types.NewSlice(typ)
+}
+
+// Enum returns the exported constants of the named type typ in
+// declaration order, or nil if typ is not an enum-like type. Enum-like
+// types are named integer or string types with at least two exported
+// constants of the type with distinct values declared in the same
+// package; they are exchanged with R as factors. The values of the
+// constants of integer types must be contiguous, as they are for iota
+// const groups, so that bit flag and identifier types are not enum-like.
+// Where more than one constant has the same value, only the first is
+// returned.
+func Enum(typ types.Type) []*types.Const {
+	named, ok := typ.(*types.Named)
+	if !ok || Temporal(named) != NotTemporal {
+		return nil
+	}
+	basic, ok := named.Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsInteger|types.IsString) == 0 {
+		return nil
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || !obj.Exported() {
+		return nil
+	}
+	scope := obj.Pkg().Scope()
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if !ok || !c.Exported() || !types.Identical(c.Type(), named) {
+			continue
+		}
+		consts = append(consts, c)
+	}
+	sort.SliceStable(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	seen := make(map[string]bool)
+	n := 0
+	for _, c := range consts {
+		v := c.Val().ExactString()
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		consts[n] = c
+		n++
+	}
+	consts = consts[:n]
+	if len(consts) < 2 {
+		return nil
+	}
+	if basic.Info()&types.IsInteger != 0 && !contiguous(consts) {
+		return nil
+	}
+	return consts
+}
+
+// contiguous returns whether the distinct integer values of consts form
+// a contiguous range.
+func contiguous(consts []*types.Const) bool {
+	vals := make([]int64, len(consts))
+	for i, c := range consts {
+		v, exact := constant.Int64Val(c.Val())
+		if !exact {
+			return false
+		}
+		vals[i] = v
+	}
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	for i := 1; i < len(vals); i++ {
+		if vals[i]-vals[i-1] != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDynamic returns whether typ is an empty interface type. Values of
+// empty interface types are exchanged with R by a runtime conversion of
+// their dynamic value.
+func IsDynamic(typ types.Type) bool {
+	iface, ok := typ.Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
+
+// Union returns the implementations of the named interface type typ in
+// name order, or nil if typ is not a sealed interface. Sealed interfaces
+// are non-empty interface types with at least one unexported method, so
+// that all their implementations are declared in the same package. They
+// are exchanged with R as the value of the implementation with an S3
+// class identifying it. Implementations are the exported non-interface
+// types of the package, or pointers to them, that implement typ. If any
+// implementation is unexported, Union returns nil.
+func Union(typ types.Type) []types.Type {
+	named, ok := typ.(*types.Named)
+	if !ok || IsError(named) {
+		return nil
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() == 0 {
+		return nil
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || !obj.Exported() {
+		return nil
+	}
+	sealed := false
+	for i := 0; i < iface.NumMethods(); i++ {
+		if !iface.Method(i).Exported() {
+			sealed = true
+			break
+		}
+	}
+	if !sealed {
+		return nil
+	}
+	scope := obj.Pkg().Scope()
+	var impls []types.Type
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		t, ok := tn.Type().(*types.Named)
+		if !ok || types.IsInterface(t) {
+			continue
+		}
+		var impl types.Type
+		switch {
+		case types.Implements(t, iface):
+			impl = t
+		case types.Implements(types.NewPointer(t), iface):
+			impl = types.NewPointer(t)
+		default:
+			continue
+		}
+		if !tn.Exported() {
+			return nil
+		}
+		impls = append(impls, impl)
+	}
+	return impls
+}
+
+// TemporalKind describes the R time class of a Go type.
+type TemporalKind int
+
+const (
+	NotTemporal TemporalKind = iota
+
+	// Time is a time.Time, exchanged as an R POSIXct.
+	Time
+
+	// Duration is a time.Duration, exchanged as an R
+	// difftime in seconds.
+	Duration
+
+	// Date is the date-only type named by Options.Date,
+	// exchanged as an R Date.
+	Date
+)
+
+// Temporal returns the time class of typ, either Time or Duration. Date
+// types depend on the analysis options; see Options.Temporal.
+func Temporal(typ types.Type) TemporalKind {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return NotTemporal
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != "time" {
+		return NotTemporal
+	}
+	switch obj.Name() {
+	case "Time":
+		return Time
+	case "Duration":
+		return Duration
+	}
+	return NotTemporal
+}
+
+// BigKind describes a math/big number type.
+type BigKind int
+
+const (
+	NotBig BigKind = iota
+
+	// BigInt is a math/big.Int, exchanged as an R character
+	// vector or a gmp bigz vector.
+	BigInt
+
+	// BigRat is a math/big.Rat, exchanged as an R character
+	// vector or a gmp bigq vector.
+	BigRat
+
+	// BigFloat is a math/big.Float, exchanged as an R character
+	// vector with the precision of each value in a prec
+	// attribute.
+	BigFloat
+)
+
+// Big returns the math/big number type of typ.
+func Big(typ types.Type) BigKind {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return NotBig
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != "math/big" {
+		return NotBig
+	}
+	switch obj.Name() {
+	case "Int":
+		return BigInt
+	case "Rat":
+		return BigRat
+	case "Float":
+		return BigFloat
+	}
+	return NotBig
+}
+
+// BigOf returns the math/big number type of typ, of the type pointed to
+// by typ, or of the elements of typ if it is a slice of either, and
+// whether the numbers are held by pointer and in a slice.
+func BigOf(typ types.Type) (kind BigKind, ptr, slice bool) {
+	if s, ok := typ.(*types.Slice); ok {
+		typ = s.Elem()
+		slice = true
+	}
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+		ptr = true
+	}
+	kind = Big(typ)
+	if kind == NotBig {
+		return NotBig, false, false
+	}
+	return kind, ptr, slice
+}
+
+// ImageKind describes an image type exchanged with R as a raster image.
+type ImageKind int
+
+const (
+	NotImage ImageKind = iota
+
+	// AnyImage is an image.Image. Values are returned to R
+	// in any image model and passed to Go as *image.NRGBA.
+	AnyImage
+
+	// RGBAImage is an *image.RGBA.
+	RGBAImage
+
+	// GrayImage is an *image.Gray.
+	GrayImage
+
+	// NRGBAImage is an *image.NRGBA.
+	NRGBAImage
+)
+
+// Image returns the image type of typ. Images are exchanged with R as
+// numeric arrays, rasters or nativeRasters.
+func Image(typ types.Type) ImageKind {
+	ptr, isPtr := typ.(*types.Pointer)
+	if isPtr {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return NotImage
+	}
+	kind := imageType(named)
+	if (kind == AnyImage) == isPtr {
+		return NotImage
+	}
+	return kind
+}
+
+// imageType returns the image type of the image package type named,
+// ignoring whether it is held by pointer.
+func imageType(named *types.Named) ImageKind {
+	obj := named.Obj()
+	if obj.Pkg() == nil || obj.Pkg().Path() != "image" {
+		return NotImage
+	}
+	switch obj.Name() {
+	case "Image":
+		return AnyImage
+	case "RGBA":
+		return RGBAImage
+	case "Gray":
+		return GrayImage
+	case "NRGBA":
+		return NRGBAImage
+	}
+	return NotImage
+}
+
+// ArrowKind describes an Arrow C data interface struct type.
+type ArrowKind int
+
+const (
+	NotArrow ArrowKind = iota
+
+	// ArrowArray is a pointer to a Go transliteration of the
+	// C data interface struct ArrowArray, exchanged as an R
+	// external pointer.
+	ArrowArray
+
+	// ArrowSchema is a pointer to a Go transliteration of the
+	// C data interface struct ArrowSchema, exchanged as an R
+	// external pointer.
+	ArrowSchema
+)
+
+// Arrow returns the Arrow C data interface struct type pointed to by typ.
+// The struct types are recognised by their name, ArrowArray or ArrowSchema,
+// and by having the field layout of the C struct of the same name, with
+// int64 fields for int64_t and pointer, unsafe.Pointer or uintptr fields
+// for pointers.
+func Arrow(typ types.Type) ArrowKind {
+	ptr, ok := typ.(*types.Pointer)
+	if !ok {
+		return NotArrow
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return NotArrow
+	}
+	return arrowType(named)
+}
+
+// arrowLayouts holds the C data interface struct layouts, with 'i'
+// for int64_t fields and 'p' for pointer fields.
+var arrowLayouts = map[string]ArrowKind{
+	"ArrowArray:iiiiippppp": ArrowArray,
+	"ArrowSchema:pppiipppp": ArrowSchema,
+}
+
+// arrowType returns the Arrow C data interface struct type of named.
+func arrowType(named *types.Named) ArrowKind {
+	s, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return NotArrow
+	}
+	layout := []byte(named.Obj().Name() + ":")
+	for i := 0; i < s.NumFields(); i++ {
+		switch typ := s.Field(i).Type().Underlying().(type) {
+		case *types.Pointer:
+			layout = append(layout, 'p')
+		case *types.Basic:
+			switch typ.Kind() {
+			case types.Int64:
+				layout = append(layout, 'i')
+			case types.UnsafePointer, types.Uintptr:
+				layout = append(layout, 'p')
+			default:
+				return NotArrow
+			}
+		default:
+			return NotArrow
+		}
+	}
+	return arrowLayouts[string(layout)]
+}
+
+// TextKind describes how a Go type with a text form is exchanged with R.
+type TextKind int
+
+const (
+	NotText TextKind = iota
+
+	// TextMarshaler is a type implementing encoding.TextMarshaler
+	// and encoding.TextUnmarshaler, exchanged as an R character
+	// scalar.
+	TextMarshaler
+
+	// Stringer is a type implementing fmt.Stringer, but not
+	// encoding.TextUnmarshaler. It is returned to R as a character
+	// scalar but cannot be passed from R.
+	Stringer
+)
+
+// hasMethod returns whether the method set of typ includes an exported
+// method with the given name, parameter and result types.
+func hasMethod(typ types.Type, name string, params, results []types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(typ, false, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return identicalTypes(sig.Params(), params) && identicalTypes(sig.Results(), results)
+}
+
+// identicalTypes returns whether the types of the variables in t are
+// identical to typs.
+func identicalTypes(t *types.Tuple, typs []types.Type) bool {
+	if t.Len() != len(typs) {
+		return false
+	}
+	for i, typ := range typs {
+		if !types.Identical(t.At(i).Type(), typ) {
+			return false
+		}
+	}
+	return true
+}
+
 func Mangle(typ types.Type) string {
 	// FIXME(kortschak): This may lead to name collisions for complex unnamed types.
 	runes := []rune(fmt.Sprintf("%T_%[1]s", typ))
@@ -8,6 +8,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -33,7 +39,7 @@ func TestAnalyse(t *testing.T) {
 			continue
 		}
 
-		info, err := Analyse(filepath.Join("github.com/rgonomic/rgo/internal/pkg", path), "", false)
+		info, err := Analyse(filepath.Join("github.com/rgonomic/rgo/internal/pkg", path), "", Options{Int64: Double}, false)
 		if err != nil {
 			t.Errorf("unexpected error during analysis of %q: %v", path, err)
 			continue
@@ -118,3 +124,449 @@ func typesFor(path string) (map[string][]string, error) {
 
 	return want, nil
 }
+
+const genericSrc = `package generic
+
+// Sum returns the sum of the values in x.
+func Sum[T float64 | int32](x []T) T {
+	var sum T
+	for _, v := range x {
+		sum += v
+	}
+	return sum
+}
+
+// Keys returns the keys of m.
+func Keys[K comparable, V any](m map[K]V) []K {
+	var keys []K
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+`
+
+var analyseGenericTests = []struct {
+	typeArgs map[string][][]string
+	want     map[string]string
+	wantErr  bool
+}{
+	{
+		typeArgs: nil,
+		want:     map[string]string{},
+	},
+	{
+		typeArgs: map[string][][]string{
+			"Sum":  {{"float64"}, {"int32"}},
+			"Keys": {{"string", "float64"}},
+		},
+		want: map[string]string{
+			"Sum_float64":         "func(x []float64) float64",
+			"Sum_int32":           "func(x []int32) int32",
+			"Keys_string_float64": "func(m map[string]float64) []string",
+		},
+	},
+	{
+		typeArgs: map[string][][]string{"Sum": {{"string"}}},
+		wantErr:  true,
+	},
+	{
+		typeArgs: map[string][][]string{"Sum": {{"float64"}, {"float64"}}},
+		wantErr:  true,
+	},
+	{
+		typeArgs: map[string][][]string{"Sum": {{"T"}}},
+		wantErr:  true,
+	},
+}
+
+func TestAnalyseGeneric(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rgo-generic-*")
+	if err != nil {
+		t.Fatalf("failed to make temporary module directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generic\n\ngo 1.18\n"), 0o664)
+	if err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "generic.go"), []byte(genericSrc), 0o664)
+	if err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	for _, test := range analyseGenericTests {
+		info, err := Analyse("generic", "", Options{TypeArgs: test.typeArgs}, false)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("unexpected error for %v: %v", test.typeArgs, err)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("expected error for %v", test.typeArgs)
+			continue
+		}
+		got := make(map[string]string)
+		for _, fn := range info.Funcs {
+			got[fn.Ident()] = fn.Signature().String()
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected instantiations for %v:\ngot: %v\nwant:%v", test.typeArgs, got, test.want)
+		}
+	}
+}
+
+const callbackSrc = `package callback
+
+type Name struct{ first string }
+
+func (n Name) String() string { return n.first }
+
+type S struct{ X Name }
+
+func F(cb func(S), s S) {}
+func G(s S)             {}
+func H(cb func() Name)  {}
+func K(cb func(S))      {}
+`
+
+func TestCheckTypeCallback(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "callback.go", callbackSrc, 0)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	pkg, err := new(types.Config).Check("callback", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("failed to type check source: %v", err)
+	}
+	// Name only has a String method, so it may be
+	// returned to R but not passed from R to Go.
+	for name, wantErr := range map[string]bool{"F": true, "G": true, "H": true, "K": false} {
+		par := pkg.Scope().Lookup(name).Type().(*types.Signature).Params()
+		err := Options{}.checkType(par, par, true)
+		if (err != nil) != wantErr {
+			t.Errorf("unexpected error for %s: got:%v want error:%t", name, err, wantErr)
+		}
+	}
+}
+
+const jsonSrc = `package json
+
+type Point struct {
+	X     float64 ` + "`json:\"x\"`" + `
+	cache []byte
+	Y     float64
+}
+`
+
+func TestJSONTagsUnexported(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "json.go", jsonSrc, 0)
+	if err != nil {
+		t.Fatalf("failed to parse source: %v", err)
+	}
+	pkg, err := new(types.Config).Check("json", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("failed to type check source: %v", err)
+	}
+	point := pkg.Scope().Lookup("Point").Type()
+
+	if err := (Options{}).checkType(point, point, true); err == nil {
+		t.Error("expected error for unexported field without JSONTags")
+	}
+	opts := Options{JSONTags: true}
+	if err := opts.checkType(point, point, true); err != nil {
+		t.Errorf("unexpected error for unexported field with JSONTags: %v", err)
+	}
+	var got []string
+	for _, f := range opts.Fields(point.Underlying().(*types.Struct)) {
+		got = append(got, f.Name)
+	}
+	want := []string{"x", "Y"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected fields: got:%v want:%v", got, want)
+	}
+}
+
+var isLiteralTests = []struct {
+	typ  types.Type
+	val  constant.Value
+	want bool
+}{
+	{typ: types.Typ[types.UntypedBool], val: constant.MakeBool(true), want: true},
+	{typ: types.Typ[types.UntypedString], val: constant.MakeString("text"), want: true},
+	{typ: types.Typ[types.String], val: constant.MakeString("nul\x00"), want: false},
+	{typ: types.Typ[types.String], val: constant.MakeString("\xff"), want: false},
+	{typ: types.Typ[types.UntypedInt], val: constant.MakeInt64(math.MaxInt32), want: true},
+	{typ: types.Typ[types.Int32], val: constant.MakeInt64(math.MinInt32), want: false},
+	{typ: types.Typ[types.Int64], val: constant.MakeInt64(1 << 40), want: false},
+	{typ: types.Typ[types.UntypedFloat], val: constant.MakeFloat64(1e-9), want: true},
+	{typ: types.Typ[types.UntypedFloat], val: constant.Shift(constant.MakeInt64(1), token.SHL, 2000), want: false},
+	{typ: types.Typ[types.UntypedComplex], val: constant.MakeImag(constant.MakeInt64(2)), want: true},
+	{typ: types.NewNamed(types.NewTypeName(token.NoPos, nil, "T", nil), types.Typ[types.Int], nil), val: constant.MakeInt64(1), want: false},
+}
+
+func TestIsLiteral(t *testing.T) {
+	for _, test := range isLiteralTests {
+		c := types.NewConst(token.NoPos, nil, "C", test.typ, test.val)
+		got := isLiteral(c)
+		if got != test.want {
+			t.Errorf("unexpected result for %s constant %s: got:%t want:%t", test.typ, test.val, got, test.want)
+		}
+	}
+}
+
+var constTypeTests = []struct {
+	typ  types.Type
+	val  constant.Value
+	want types.Type
+}{
+	{typ: types.Typ[types.UntypedInt], val: constant.MakeInt64(1), want: types.Typ[types.Int]},
+	{typ: types.Typ[types.UntypedInt], val: constant.MakeInt64(1 << 40), want: types.Typ[types.Int64]},
+	{typ: types.Typ[types.UntypedInt], val: constant.MakeInt64(math.MinInt32), want: types.Typ[types.Int64]},
+	{typ: types.Typ[types.UntypedInt], val: constant.Shift(constant.MakeInt64(1), token.SHL, 100), want: nil},
+	{typ: types.Typ[types.UntypedRune], val: constant.MakeInt64(1 << 40), want: nil},
+	{typ: types.Typ[types.UntypedFloat], val: constant.MakeFloat64(1e-9), want: types.Typ[types.Float64]},
+	{typ: types.Typ[types.UntypedFloat], val: constant.MakeFromLiteral("1e400", token.FLOAT, 0), want: nil},
+	{typ: types.Typ[types.UntypedComplex], val: constant.MakeImag(constant.MakeFromLiteral("1e400", token.FLOAT, 0)), want: nil},
+	{typ: types.Typ[types.Int64], val: constant.MakeInt64(1 << 40), want: types.Typ[types.Int64]},
+}
+
+func TestConstType(t *testing.T) {
+	for _, test := range constTypeTests {
+		c := types.NewConst(token.NoPos, nil, "C", test.typ, test.val)
+		got := constType(c)
+		if got != test.want {
+			t.Errorf("unexpected result for %s constant %s: got:%v want:%v", test.typ, test.val, got, test.want)
+		}
+	}
+}
+
+var arrowTests = []struct {
+	name   string
+	fields []types.Type
+	want   ArrowKind
+}{
+	{
+		name:   "ArrowSchema",
+		fields: arrowFields("pppiipppp"),
+		want:   ArrowSchema,
+	},
+	{
+		name:   "ArrowArray",
+		fields: arrowFields("iiiiippppp"),
+		want:   ArrowArray,
+	},
+	{
+		name:   "ArrowArray",
+		fields: append(arrowFields("iiiiipppp"), types.Typ[types.Int32]),
+		want:   NotArrow,
+	},
+	{
+		name:   "ArrowArray",
+		fields: arrowFields("pppiipppp"),
+		want:   NotArrow,
+	},
+	{
+		name:   "Array",
+		fields: arrowFields("iiiiippppp"),
+		want:   NotArrow,
+	},
+}
+
+// arrowFields returns field types following the layout, with 'i' for
+// int64 fields and 'p' for unsafe.Pointer fields.
+func arrowFields(layout string) []types.Type {
+	fields := make([]types.Type, len(layout))
+	for i, f := range layout {
+		switch f {
+		case 'i':
+			fields[i] = types.Typ[types.Int64]
+		case 'p':
+			fields[i] = types.Typ[types.UnsafePointer]
+		}
+	}
+	return fields
+}
+
+func TestArrow(t *testing.T) {
+	for _, test := range arrowTests {
+		vars := make([]*types.Var, len(test.fields))
+		for i, typ := range test.fields {
+			vars[i] = types.NewField(token.NoPos, nil, fmt.Sprintf("F%d", i), typ, false)
+		}
+		named := types.NewNamed(types.NewTypeName(token.NoPos, nil, test.name, nil), types.NewStruct(vars, nil), nil)
+		if got := Arrow(named); got != NotArrow {
+			t.Errorf("unexpected result for %s value type: got:%d want:%d", test.name, got, NotArrow)
+		}
+		got := Arrow(types.NewPointer(named))
+		if got != test.want {
+			t.Errorf("unexpected result for %s with fields %v: got:%d want:%d", test.name, test.fields, got, test.want)
+		}
+	}
+}
+
+var frameTests = []struct {
+	name string
+	typ  types.Type
+	want FrameKind
+}{
+	{
+		name: "rows",
+		typ: types.NewSlice(types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "A", types.Typ[types.Float64], false),
+			types.NewField(token.NoPos, nil, "B", types.Typ[types.String], false),
+		}, nil)),
+		want: RowFrame,
+	},
+	{
+		name: "columns",
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "_", types.NewStruct(nil, nil), false),
+			types.NewField(token.NoPos, nil, "A", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(token.NoPos, nil, "B", types.NewSlice(types.Typ[types.String]), false),
+		}, []string{`rgo:",frame"`}),
+		want: ColumnFrame,
+	},
+	{
+		name: "one column",
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "_", types.NewStruct(nil, nil), false),
+			types.NewField(token.NoPos, nil, "X", types.NewSlice(types.Typ[types.Float64]), false),
+		}, []string{`rgo:",frame"`}),
+		want: ColumnFrame,
+	},
+	{
+		name: "untagged columns",
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "A", types.NewSlice(types.Typ[types.Float64]), false),
+			types.NewField(token.NoPos, nil, "B", types.NewSlice(types.Typ[types.String]), false),
+		}, nil),
+		want: NotFrame,
+	},
+	{
+		name: "tagged non-columns",
+		typ: types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "_", types.NewStruct(nil, nil), false),
+			types.NewField(token.NoPos, nil, "A", types.Typ[types.Float64], false),
+		}, []string{`rgo:",frame"`}),
+		want: NotFrame,
+	},
+}
+
+func TestFrame(t *testing.T) {
+	var opts Options
+	for _, test := range frameTests {
+		got := opts.Frame(test.typ)
+		if got != test.want {
+			t.Errorf("unexpected result for %s: got:%d want:%d", test.name, got, test.want)
+		}
+	}
+}
+
+var enumTests = []struct {
+	name   string
+	typ    types.Type
+	consts []constant.Value
+	want   bool
+}{
+	{
+		name:   "Color",
+		typ:    types.Typ[types.Int],
+		consts: []constant.Value{constant.MakeInt64(0), constant.MakeInt64(1), constant.MakeInt64(2)},
+		want:   true,
+	},
+	{
+		name:   "Weekday",
+		typ:    types.Typ[types.Int],
+		consts: []constant.Value{constant.MakeInt64(2), constant.MakeInt64(1), constant.MakeInt64(3), constant.MakeInt64(1)},
+		want:   true,
+	},
+	{
+		name:   "Shape",
+		typ:    types.Typ[types.String],
+		consts: []constant.Value{constant.MakeString("circle"), constant.MakeString("square")},
+		want:   true,
+	},
+	{
+		name:   "Flags",
+		typ:    types.Typ[types.Uint8],
+		consts: []constant.Value{constant.MakeInt64(1), constant.MakeInt64(2), constant.MakeInt64(4)},
+		want:   false,
+	},
+	{
+		name:   "ID",
+		typ:    types.Typ[types.Int],
+		consts: []constant.Value{constant.MakeInt64(0)},
+		want:   false,
+	},
+	{
+		name:   "Path",
+		typ:    types.Typ[types.String],
+		consts: []constant.Value{constant.MakeString("/")},
+		want:   false,
+	},
+	{
+		name:   "Alias",
+		typ:    types.Typ[types.Int],
+		consts: []constant.Value{constant.MakeInt64(0), constant.MakeInt64(0)},
+		want:   false,
+	},
+}
+
+func TestEnum(t *testing.T) {
+	for _, test := range enumTests {
+		pkg := types.NewPackage("path/to/pkg", "pkg")
+		named := types.NewNamed(types.NewTypeName(token.NoPos, pkg, test.name, nil), test.typ, nil)
+		pkg.Scope().Insert(named.Obj())
+		for i, v := range test.consts {
+			pkg.Scope().Insert(types.NewConst(token.Pos(i+1), pkg, fmt.Sprintf("C%d", i), named, v))
+		}
+		got := Enum(named) != nil
+		if got != test.want {
+			t.Errorf("unexpected enum-like result for %s: got:%t want:%t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFieldsEmbeddedPointer(t *testing.T) {
+	pkg := types.NewPackage("path/to/pkg", "pkg")
+	base := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Base", nil), types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, pkg, "ID", types.Typ[types.Int], false),
+		types.NewField(token.NoPos, pkg, "Name", types.Typ[types.String], false),
+	}, nil), nil)
+	node := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Node", nil), nil, nil)
+	node.SetUnderlying(types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, pkg, "Node", types.NewPointer(node), true),
+		types.NewField(token.NoPos, pkg, "Base", types.NewPointer(base), true),
+		types.NewField(token.NoPos, pkg, "Name", types.Typ[types.String], false),
+	}, nil))
+
+	type field struct{ Name, Path, Via string }
+	var got []field
+	for _, f := range (Options{}).Fields(node.Underlying().(*types.Struct)) {
+		var via []string
+		for _, v := range f.Via {
+			via = append(via, v.Path)
+		}
+		got = append(got, field{Name: f.Name, Path: f.Path, Via: strings.Join(via, ",")})
+	}
+	want := []field{
+		{Name: "ID", Path: "Base.ID", Via: "Base"},
+		{Name: "Name", Path: "Name"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected fields:\ngot: %v\nwant:%v", got, want)
+	}
+}
@@ -74,12 +74,22 @@ func (b *build) Run(ctx context.Context, args ...string) error {
 		return fmt.Errorf("failed to parse license name pattern: %w", err)
 	}
 
-	info, err := pkg.Analyse(b.Config.PkgPath, b.Config.AllowedFuncs, b.app.Verbose)
+	info, err := pkg.Analyse(b.Config.PkgPath, b.Config.AllowedFuncs, pkg.Options{
+		Int64:        pkg.Int64Mode(b.Config.Int64),
+		NASentinel:   b.Config.NASentinel,
+		Date:         b.Config.Date,
+		JSONTags:     b.Config.JSONTags,
+		Classes:      b.Config.Classes,
+		ClassNames:   b.Config.ClassNames,
+		GMP:          b.Config.GMP,
+		NativeRaster: b.Config.NativeRaster,
+		TypeArgs:     b.Config.TypeArgs,
+	}, b.app.Verbose)
 	if err != nil {
 		return fmt.Errorf("load error: %w", err)
 	}
-	if len(info.Funcs) == 0 {
-		log.Println("no functions to wrap")
+	if len(info.Funcs) == 0 && len(info.Consts) == 0 {
+		log.Println("no functions or constants to wrap")
 		return nil
 	}
 	exported, err := regexp.Compile(b.Exported)
@@ -103,10 +103,75 @@ type Config struct {
 	PkgPath string
 
 	// AllowedFuncs is a pattern matching names of
-	// functions that may be wrapped. If AllowedFuncs
-	// is empty all wrappable functions are wrapped.
+	// functions, constants and variables that may be
+	// wrapped. Methods are matched by their qualified
+	// name, "T.Method". If AllowedFuncs is empty all
+	// wrappable functions, constants and variables
+	// are wrapped.
 	AllowedFuncs string
 
+	// Int64 is the R representation of Go int64 and
+	// uint64 values. It must be one of "integer64",
+	// "double" or "character". The "integer64"
+	// representation requires the bit64 R package and
+	// the "double" representation is checked for
+	// exactness beyond 2^53. If Int64 is empty,
+	// functions using 64-bit integers are not wrapped.
+	Int64 string
+
+	// NASentinel allows R NA values to be passed to Go
+	// as the value R uses to represent NA for the type,
+	// rather than raising an error. Logical NA values
+	// always raise an error.
+	NASentinel bool
+
+	// Date is the qualified name, "path/to/pkg.Type",
+	// of a date-only type to be exchanged with R as a
+	// Date. The type must be a struct with Year int,
+	// Month time.Month and Day int fields, for example
+	// cloud.google.com/go/civil.Date. If Date is empty,
+	// no type is treated as a date.
+	Date string
+
+	// JSONTags allows json struct tags to name, skip
+	// and set options for struct fields that do not
+	// have an rgo struct tag. Unexported fields are
+	// skipped as they are by encoding/json.
+	JSONTags bool
+
+	// Classes gives R values of named Go types an S3
+	// class of the package-qualified Go type name,
+	// "pkg.Type", and checks the class of values
+	// passed to Go.
+	Classes bool
+
+	// ClassNames maps qualified Go type names,
+	// "path/to/pkg.Type", to the S3 class of their
+	// R values. Types in ClassNames are given a class
+	// even if Classes is false.
+	ClassNames map[string]string
+
+	// GMP represents math/big Int and Rat values as
+	// gmp bigz and bigq vectors rather than character
+	// vectors. Values passed to Go may be in either
+	// representation.
+	GMP bool
+
+	// NativeRaster represents images returned to R as
+	// nativeRaster integer matrices rather than double
+	// arrays with dim c(height, width, 4). Images passed
+	// to Go may be in either representation or rasters.
+	NativeRaster bool
+
+	// TypeArgs maps the names of generic functions
+	// to the lists of type arguments used to
+	// instantiate them. Each type argument is a Go
+	// type expression evaluated in the scope of the
+	// function's source file, for example "float64"
+	// or "big.Int". Generic functions without type
+	// arguments are not wrapped.
+	TypeArgs map[string][][]string
+
 	// Words is a set of known words that can be provided
 	// to ensure camel-case to snake case breaks words
 	// correctly. If words is nil, "NaN" and "NA" are
@@ -18,6 +18,7 @@ import (
 	"path/filepath"
 
 	"github.com/rgonomic/rgo/internal/mod"
+	"github.com/rgonomic/rgo/internal/pkg"
 )
 
 // setup implements the setup command.
@@ -80,6 +81,7 @@ func (s *setup) Run(ctx context.Context, args ...string) error {
 
 	cfg := Config{
 		PkgPath:        filepath.ToSlash(path),
+		Int64:          string(pkg.Double),
 		LicenseDir:     "LICENSE",
 		LicensePattern: `(LICEN[SC]E|COPYING)(\.(txt|md))?$`,
 	}
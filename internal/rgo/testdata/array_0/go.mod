@@ -0,0 +1,3 @@
+module array_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module arrow_0
+
+go 1.15
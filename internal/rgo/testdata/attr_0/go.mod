@@ -0,0 +1,3 @@
+module attr_0
+
+go 1.15
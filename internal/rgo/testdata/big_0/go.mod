@@ -0,0 +1,3 @@
+module big_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module callback_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module class_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module dynamic_0
+
+go 1.15
@@ -0,0 +1,31 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package embedded_0
+
+type (
+	Base struct {
+		ID   int
+		Name string
+	}
+	Outer struct {
+		Base
+		Name  string
+		Other Base `rgo:"other"`
+	}
+	Ref struct {
+		*Base
+		Note string
+	}
+)
+
+// Test0 does things with [Outer] and returns [Outer].
+func Test0(par0 Outer) Outer {
+	var res0 Outer
+	return res0
+}
+
+// Test1 does things with [Ref] and returns [Ref].
+func Test1(par0 Ref) Ref {
+	var res0 Ref
+	return res0
+}
@@ -0,0 +1,3 @@
+module embedded_0
+
+go 1.15
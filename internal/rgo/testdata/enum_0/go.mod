@@ -0,0 +1,3 @@
+module enum_0
+
+go 1.15
@@ -0,0 +1,36 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package frame_0
+
+type (
+	Row struct {
+		Name  string `rgo:"name"`
+		Count int
+	}
+	Cols struct {
+		_     struct{} `rgo:",frame"`
+		Name  []string `rgo:"name"`
+		Count []int
+	}
+	Col struct {
+		_ struct{} `rgo:",frame"`
+		X []float64
+	}
+	Pair struct {
+		A []float64
+		B []string
+	}
+)
+
+// Test0 does things with [[]Row] and returns [Cols].
+func Test0(par0 []Row) Cols {
+	var res0 Cols
+	return res0
+}
+
+// Test1 does things with [Col Pair] and returns [Col Pair].
+func Test1(par0 Col, par1 Pair) (Col, Pair) {
+	var res0 Col
+	var res1 Pair
+	return res0, res1
+}
@@ -0,0 +1,3 @@
+module frame_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module handle_0
+
+go 1.15
@@ -0,0 +1,30 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package handle_0
+
+type (
+	H struct{ n int }
+	P struct{ N int }
+)
+
+// Test0 does things with [] and returns [*H].
+func Test0() *H {
+	var res0 *H
+	return res0
+}
+
+// Test1 does things with [int] and returns [int].
+func (recv *H) Test1(par0 int) int {
+	var res0 int
+	return res0
+}
+
+// Test2 does things with [*H] and returns [].
+func (recv H) Test2(par0 *H) {
+}
+
+// Test3 does things with [] and returns [int].
+func (recv *P) Test3() int {
+	var res0 int
+	return res0
+}
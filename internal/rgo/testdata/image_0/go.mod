@@ -0,0 +1,3 @@
+module image_0
+
+go 1.15
@@ -0,0 +1,19 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package image_0
+
+import (
+	"image"
+)
+
+// Test0 does things with [*image.RGBA image.Image] and returns [image.Image].
+func Test0(par0 *image.RGBA, par1 image.Image) image.Image {
+	var res0 image.Image
+	return res0
+}
+
+// Test1 does things with [*image.Gray] and returns [*image.NRGBA].
+func Test1(par0 *image.Gray) *image.NRGBA {
+	var res0 *image.NRGBA
+	return res0
+}
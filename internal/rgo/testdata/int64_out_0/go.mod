@@ -0,0 +1,3 @@
+module int64_out_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module int64_slice_out_0
+
+go 1.15
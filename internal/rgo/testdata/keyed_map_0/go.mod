@@ -0,0 +1,3 @@
+module keyed_map_0
+
+go 1.15
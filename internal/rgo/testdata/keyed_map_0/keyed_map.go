@@ -0,0 +1,22 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package keyed_map_0
+
+type (
+	ID    int
+	Key   string
+	Label string
+)
+
+// Test0 does things with [map[ID]string map[Key]float64] and returns [map[int]struct{} map[float64]bool].
+func Test0(par0 map[ID]string, par1 map[Key]float64) (map[int]struct{}, map[float64]bool) {
+	var res0 map[int]struct{}
+	var res1 map[float64]bool
+	return res0, res1
+}
+
+// Test1 does things with [map[ID]struct{}] and returns [map[ID]Label].
+func Test1(par0 map[ID]struct{}) map[ID]Label {
+	var res0 map[ID]Label
+	return res0
+}
@@ -0,0 +1,3 @@
+module nullable_0
+
+go 1.15
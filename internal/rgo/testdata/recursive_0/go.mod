@@ -0,0 +1,3 @@
+module recursive_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module string_uint64_map_in_0
+
+go 1.15
@@ -0,0 +1,9 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package string_uint64_map_out_0
+
+// Test0 does things with [] and returns [map[string]uint64].
+func Test0() map[string]uint64 {
+	var res0 map[string]uint64
+	return res0
+}
@@ -0,0 +1,3 @@
+module string_uint64_map_out_named_0
+
+go 1.15
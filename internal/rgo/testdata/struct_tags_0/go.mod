@@ -0,0 +1,3 @@
+module struct_tags_0
+
+go 1.15
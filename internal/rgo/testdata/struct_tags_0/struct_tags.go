@@ -0,0 +1,23 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package struct_tags_0
+
+import (
+	"sync"
+)
+
+type (
+	Range struct{ Min, Max float64 }
+	Stats struct {
+		Mean  float64    `rgo:"mean"`
+		Notes []string   `rgo:"notes,omitempty"`
+		Range Range      `rgo:",inline"`
+		mu    sync.Mutex `rgo:"-"`
+	}
+)
+
+// Test0 does things with [*Stats] and returns [*Stats].
+func Test0(par0 *Stats) *Stats {
+	var res0 *Stats
+	return res0
+}
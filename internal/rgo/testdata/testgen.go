@@ -46,18 +46,235 @@ var crafted = []pkg{
 			{In: []string{"map[string][]float64"}, Out: []string{"map[string][]float64"}, Named: false},
 		},
 	},
+	{
+		Name:  "handle",
+		Path:  "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{"H struct{ n int }", "P struct{ N int }"},
+		Funcs: []fn{
+			{Out: []string{"*H"}},
+			{Recv: "*H", In: []string{"int"}, Out: []string{"int"}},
+			{Recv: "H", In: []string{"*H"}},
+			{Recv: "*P", Out: []string{"int"}},
+		},
+	},
+	{
+		Name: "frame",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{
+			"Row struct{ Name string `rgo:\"name\"`; Count int }",
+			"Cols struct{ _ struct{} `rgo:\",frame\"`; Name []string `rgo:\"name\"`; Count []int }",
+			"Col struct{ _ struct{} `rgo:\",frame\"`; X []float64 }",
+			"Pair struct{ A []float64; B []string }",
+		},
+		Funcs: []fn{
+			{In: []string{"[]Row"}, Out: []string{"Cols"}, Named: false},
+			{In: []string{"Col", "Pair"}, Out: []string{"Col", "Pair"}, Named: false},
+		},
+	},
+	{
+		Name:    "time",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"time"},
+		Funcs: []fn{
+			{In: []string{"time.Time", "[]time.Duration"}, Out: []string{"[]time.Time", "time.Duration"}, Named: false},
+		},
+	},
+	{
+		Name:    "nullable",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"database/sql"},
+		Funcs: []fn{
+			{In: []string{"*float64", "sql.NullInt32"}, Out: []string{"sql.NullString", "*bool"}, Named: false},
+		},
+	},
+	{
+		Name: "recursive",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{
+			"Node struct{ Name string; Children []*Node }",
+			"List struct{ Value float64; Next *List }",
+		},
+		Funcs: []fn{
+			{In: []string{"*Node"}, Out: []string{"[]*Node", "List"}, Named: false},
+		},
+	},
+	{
+		Name: "embedded",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{
+			"Base struct{ ID int; Name string }",
+			"Outer struct{ Base; Name string; Other Base `rgo:\"other\"` }",
+			"Ref struct{ *Base; Note string }",
+		},
+		Funcs: []fn{
+			{In: []string{"Outer"}, Out: []string{"Outer"}, Named: false},
+			{In: []string{"Ref"}, Out: []string{"Ref"}, Named: false},
+		},
+	},
+	{
+		Name:    "struct_tags",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"sync"},
+		Types: []string{
+			"Range struct{ Min, Max float64 }",
+			"Stats struct{ Mean float64 `rgo:\"mean\"`; Notes []string `rgo:\"notes,omitempty\"`; Range Range `rgo:\",inline\"`; mu sync.Mutex `rgo:\"-\"` }",
+		},
+		Funcs: []fn{
+			{In: []string{"*Stats"}, Out: []string{"*Stats"}, Named: false},
+		},
+	},
+	{
+		Name:  "attr",
+		Path:  "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{"Estimate struct{ Values []float64; Units string `rgo:\"units,attr\"`; Iterations int `rgo:\"iterations,attr\"`; Converged bool `rgo:\"converged,attr\"` }"},
+		Funcs: []fn{
+			{In: []string{"Estimate"}, Out: []string{"Estimate"}, Named: false},
+		},
+	},
+	{
+		Name:  "class",
+		Path:  "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{"WordStats struct{ _ struct{} `rgo:\"wordstats,class\"`; Words int; Lines int }"},
+		Funcs: []fn{
+			{In: []string{"*WordStats"}, Out: []string{"WordStats"}, Named: false},
+		},
+	},
+	{
+		Name: "union",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{
+			"Shape interface{ isShape() }",
+			"Circle struct{ Radius float64 }",
+			"Rect struct{ Width, Height float64 }",
+		},
+		Methods: []string{"func (Circle) isShape() {}", "func (*Rect) isShape() {}"},
+		Funcs: []fn{
+			{In: []string{"Shape"}, Out: []string{"[]Shape"}, Named: false},
+		},
+	},
+	{
+		Name: "dynamic",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Funcs: []fn{
+			{In: []string{"interface{}", "map[string]interface{}"}, Out: []string{"[]interface{}"}, Named: false},
+		},
+	},
+	{
+		Name:    "text",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"net", "net/url"},
+		Funcs: []fn{
+			{In: []string{"net.IP", "[]net.IP"}, Out: []string{"[]net.IP", "*url.URL"}, Named: false},
+		},
+	},
+	{
+		Name:    "big",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"math/big"},
+		Funcs: []fn{
+			{In: []string{"*big.Int", "[]*big.Rat", "big.Float"}, Out: []string{"[]*big.Int", "*big.Rat", "*big.Float"}, Named: false},
+		},
+	},
+	{
+		Name:    "values",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"time"},
+		Consts: []string{
+			"// Release is the release version.\n\tRelease = \"v1.2.0\"",
+			"Tolerance = 1e-9",
+			"MaxIter int = 100",
+			"Huge int64 = 1 << 40",
+			"Big = 1 << 40",
+			"Overflow = 1 << 100",
+			"Inf = 1e400",
+			"Timeout = 5 * time.Second",
+			"unexported = 1",
+		},
+		Vars: []string{"// Verbose enables logging.\n\tVerbose bool", "Weights []float64"},
+		Funcs: []fn{
+			{In: []string{"int"}, Out: []string{"float64"}, Named: false},
+		},
+	},
+	{
+		Name:    "image",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"image"},
+		Funcs: []fn{
+			{In: []string{"*image.RGBA", "image.Image"}, Out: []string{"image.Image"}, Named: false},
+			{In: []string{"*image.Gray"}, Out: []string{"*image.NRGBA"}, Named: false},
+		},
+	},
+	{
+		Name:    "arrow",
+		Path:    "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Imports: []string{"unsafe"},
+		Types: []string{
+			"ArrowSchema struct{ Format, Name, Metadata *byte; Flags, NChildren int64; Children **ArrowSchema; Dictionary *ArrowSchema; Release uintptr; PrivateData unsafe.Pointer }",
+			"ArrowArray struct{ Length, NullCount, Offset, NBuffers, NChildren int64; Buffers *unsafe.Pointer; Children **ArrowArray; Dictionary *ArrowArray; Release uintptr; PrivateData unsafe.Pointer }",
+		},
+		Funcs: []fn{
+			{In: []string{"*ArrowArray", "*ArrowSchema"}, Out: []string{"*ArrowArray", "*ArrowSchema"}, Named: false},
+		},
+	},
+	{
+		Name: "array",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Funcs: []fn{
+			{In: []string{"[3][4]float64", "[2][2][2]int32"}, Out: []string{"[2][3]string"}, Named: false},
+		},
+	},
+	{
+		Name: "callback",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Funcs: []fn{
+			{In: []string{"func(float64) float64", "float64"}, Out: []string{"float64"}, Named: false},
+			{In: []string{"func([]float64) (float64, error)"}, Out: []string{"error"}, Named: false},
+		},
+	},
+	{
+		Name: "variadic",
+		Path: "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Funcs: []fn{
+			{In: []string{"string", "...float64"}, Out: []string{"string"}, Named: false},
+			{In: []string{"...[]int"}, Out: []string{"int"}, Named: false},
+		},
+	},
+	{
+		Name:  "keyed_map",
+		Path:  "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types: []string{"ID int", "Key string", "Label string"},
+		Funcs: []fn{
+			{In: []string{"map[ID]string", "map[Key]float64"}, Out: []string{"map[int]struct{}", "map[float64]bool"}, Named: false},
+			{In: []string{"map[ID]struct{}"}, Out: []string{"map[ID]Label"}, Named: false},
+		},
+	},
+	{
+		Name:   "enum",
+		Path:   "github.com/rgonomic/rgo/internal/rgo/testdata",
+		Types:  []string{"Color int", "Shape string", "Flags uint8", "ID int"},
+		Consts: []string{"Red Color = iota", "Green", "Blue", `Circle Shape = "circle"`, `Square Shape = "square"`, "FlagA Flags = 1 << iota", "FlagB", "FlagC", "NoID ID = 0"},
+		Funcs: []fn{
+			{In: []string{"Color", "[]Shape"}, Out: []string{"[]Color", "Shape"}, Named: false},
+			{In: []string{"Flags", "ID"}, Out: []string{"Flags"}, Named: false},
+		},
+	},
 }
 
 type pkg struct {
-	Name  string
-	UID   int
-	Path  string
-	Types []string
-	Funcs []fn
+	Name    string
+	UID     int
+	Path    string
+	Imports []string
+	Types   []string
+	Consts  []string
+	Vars    []string
+	Methods []string // Method declarations.
+	Funcs   []fn
 }
 
 type fn struct {
 	pkg   *pkg
+	Recv  string   // Receiver type for methods.
 	In    []string // Input parameter types.
 	Out   []string // Output parameter types.
 	Named bool     // Whether the output parameter types are named.
@@ -66,8 +283,6 @@ type fn struct {
 func builtins() []pkg {
 	skip := map[types.BasicKind]bool{
 		types.Uintptr: true,
-		types.Uint64:  true,
-		types.Int64:   true,
 	}
 	var pkgs []pkg
 	for t := types.Bool; t <= types.String; t++ {
@@ -195,15 +410,33 @@ func main() {
 var src = template.Must(template.New("Go source").Parse(`// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
 
 package {{.Name}}_{{.UID}}
-{{if .Types}}
+{{if .Imports}}
+import (
+{{- range $i, $p := .Imports}}
+	"{{$p}}"{{end}}
+)
+{{end}}{{if .Types}}
 type (
 {{- range $i, $t := .Types}}
 	{{$t}}{{end}}
+){{end}}{{if .Consts}}
+
+const (
+{{- range $i, $c := .Consts}}
+	{{$c}}{{end}}
+){{end}}{{if .Vars}}
+
+var (
+{{- range $i, $v := .Vars}}
+	{{$v}}{{end}}
 ){{end}}
+{{- range $i, $m := .Methods}}
+
+{{$m}}{{end}}
 {{- range $i, $fn := .Funcs}}
 
 // Test{{$i}} does things with {{$fn.In}} and returns {{$fn.Out}}.
-func Test{{$i}}({{if $fn.In}}{{range $j, $p := $fn.In -}}
+func {{if $fn.Recv}}(recv {{$fn.Recv}}) {{end}}Test{{$i}}({{if $fn.In}}{{range $j, $p := $fn.In -}}
 	{{- if ne $j 0}}, {{end}}par{{$j}} {{$p -}}
 {{- end}}{{end}}){{if $fn.Out}} ({{range $j, $p := $fn.Out -}}
 	{{- if ne $j 0}}, {{end}}{{if $fn.Named}}res{{$j}} {{end}}{{$p}}{{end}}){{end}} { {{if not $fn.Named}}{{range $j, $p := $fn.Out}}
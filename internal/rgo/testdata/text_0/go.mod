@@ -0,0 +1,3 @@
+module text_0
+
+go 1.15
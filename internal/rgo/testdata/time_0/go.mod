@@ -0,0 +1,3 @@
+module time_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module uint64_array_in_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module uint64_in_0
+
+go 1.15
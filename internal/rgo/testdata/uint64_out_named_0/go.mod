@@ -0,0 +1,3 @@
+module uint64_out_named_0
+
+go 1.15
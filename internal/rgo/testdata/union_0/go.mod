@@ -0,0 +1,3 @@
+module union_0
+
+go 1.15
@@ -0,0 +1,3 @@
+module values_0
+
+go 1.15
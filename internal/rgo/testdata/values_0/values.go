@@ -0,0 +1,32 @@
+// Code generated by "go generate github.com/rgonomic/rgo/internal/pkg/testdata"; DO NOT EDIT.
+
+package values_0
+
+import (
+	"time"
+)
+
+const (
+	// Release is the release version.
+	Release          = "v1.2.0"
+	Tolerance        = 1e-9
+	MaxIter    int   = 100
+	Huge       int64 = 1 << 40
+	Big              = 1 << 40
+	Overflow         = 1 << 100
+	Inf              = 1e400
+	Timeout          = 5 * time.Second
+	unexported       = 1
+)
+
+var (
+	// Verbose enables logging.
+	Verbose bool
+	Weights []float64
+)
+
+// Test0 does things with [int] and returns [float64].
+func Test0(par0 int) float64 {
+	var res0 float64
+	return res0
+}
@@ -0,0 +1,3 @@
+module variadic_0
+
+go 1.15
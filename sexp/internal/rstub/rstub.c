@@ -0,0 +1,230 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include <math.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "rstub.h"
+
+// attr is an element of the attribute list of a value.
+struct attr {
+	SEXP name;
+	SEXP value;
+	struct attr *next;
+};
+
+// SEXPREC is a minimal R value. Values are never freed.
+struct SEXPREC {
+	int type;
+	R_xlen_t length;
+	void *data;
+	struct attr *attrib;
+	int preserved;
+};
+
+// symbols is the list of installed symbols.
+static struct symbol {
+	SEXP sym;
+	struct symbol *next;
+} *symbols;
+
+static struct SEXPREC nil = {NILSXP, 0, NULL, NULL, 0};
+static struct SEXPREC na_string = {CHARSXP, 2, "NA", NULL, 0};
+static struct SEXPREC blank_string = {CHARSXP, 0, "", NULL, 0};
+
+SEXP R_NilValue = &nil;
+SEXP R_NaString = &na_string;
+double R_NaReal;
+
+// R's NA_real_ is a NaN with 1954 in its low word.
+static const uint64_t na_bits = 0x7ff00000000007a2;
+
+__attribute__((constructor)) static void init(void) {
+	memcpy(&R_NaReal, &na_bits, sizeof R_NaReal);
+}
+
+int R_IsNA(double x) {
+	uint64_t bits;
+	memcpy(&bits, &x, sizeof bits);
+	return isnan(x) && (uint32_t)bits == 1954;
+}
+
+int TYPEOF(SEXP x) {
+	return x->type;
+}
+
+R_xlen_t Rf_xlength(SEXP x) {
+	return x->length;
+}
+
+SEXP Rf_install(const char *name) {
+	for (struct symbol *s = symbols; s != NULL; s = s->next) {
+		if (strcmp(s->sym->data, name) == 0) {
+			return s->sym;
+		}
+	}
+	SEXP sym = calloc(1, sizeof(struct SEXPREC));
+	sym->type = SYMSXP;
+	sym->data = strdup(name);
+	struct symbol *s = malloc(sizeof(struct symbol));
+	s->sym = sym;
+	s->next = symbols;
+	symbols = s;
+	return sym;
+}
+
+SEXP Rf_getAttrib(SEXP x, SEXP name) {
+	for (struct attr *a = x->attrib; a != NULL; a = a->next) {
+		if (a->name == name) {
+			return a->value;
+		}
+	}
+	return R_NilValue;
+}
+
+SEXP Rf_setAttrib(SEXP x, SEXP name, SEXP val) {
+	for (struct attr **a = &x->attrib; *a != NULL; a = &(*a)->next) {
+		if ((*a)->name != name) {
+			continue;
+		}
+		if (val == R_NilValue) {
+			*a = (*a)->next;
+		} else {
+			(*a)->value = val;
+		}
+		return val;
+	}
+	if (val != R_NilValue) {
+		struct attr *a = malloc(sizeof(struct attr));
+		a->name = name;
+		a->value = val;
+		a->next = x->attrib;
+		x->attrib = a;
+	}
+	return val;
+}
+
+int Rf_inherits(SEXP x, const char *class) {
+	SEXP cls = Rf_getAttrib(x, Rf_install("class"));
+	if (cls->type != STRSXP) {
+		return 0;
+	}
+	for (R_xlen_t i = 0; i < cls->length; i++) {
+		if (strcmp(STRING_ELT(cls, i)->data, class) == 0) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+SEXP Rf_allocVector(unsigned int type, R_xlen_t n) {
+	size_t size;
+	switch (type) {
+	case LGLSXP:
+	case INTSXP:
+		size = sizeof(int);
+		break;
+	case REALSXP:
+		size = sizeof(double);
+		break;
+	case CPLXSXP:
+		size = sizeof(Rcomplex);
+		break;
+	case RAWSXP:
+		size = 1;
+		break;
+	case STRSXP:
+	case VECSXP:
+	case EXPRSXP:
+		size = sizeof(SEXP);
+		break;
+	default:
+		abort();
+	}
+	SEXP x = calloc(1, sizeof(struct SEXPREC));
+	x->type = type;
+	x->length = n;
+	x->data = calloc(n == 0 ? 1 : n, size);
+	for (R_xlen_t i = 0; i < n; i++) {
+		switch (type) {
+		case STRSXP:
+			((SEXP *)x->data)[i] = &blank_string;
+			break;
+		case VECSXP:
+		case EXPRSXP:
+			((SEXP *)x->data)[i] = R_NilValue;
+			break;
+		}
+	}
+	return x;
+}
+
+int *LOGICAL(SEXP x) {
+	return x->data;
+}
+
+int *INTEGER(SEXP x) {
+	return x->data;
+}
+
+double *REAL(SEXP x) {
+	return x->data;
+}
+
+Rcomplex *COMPLEX(SEXP x) {
+	return x->data;
+}
+
+unsigned char *RAW(SEXP x) {
+	return x->data;
+}
+
+SEXP STRING_ELT(SEXP x, R_xlen_t i) {
+	return ((SEXP *)x->data)[i];
+}
+
+void SET_STRING_ELT(SEXP x, R_xlen_t i, SEXP v) {
+	((SEXP *)x->data)[i] = v;
+}
+
+SEXP VECTOR_ELT(SEXP x, R_xlen_t i) {
+	return ((SEXP *)x->data)[i];
+}
+
+SEXP SET_VECTOR_ELT(SEXP x, R_xlen_t i, SEXP v) {
+	((SEXP *)x->data)[i] = v;
+	return v;
+}
+
+const char *Rf_translateCharUTF8(SEXP x) {
+	return x->data;
+}
+
+SEXP Rf_mkCharLenCE(const char *s, int len, int enc) {
+	SEXP x = calloc(1, sizeof(struct SEXPREC));
+	x->type = CHARSXP;
+	x->length = len;
+	char *data = malloc(len + 1);
+	memcpy(data, s, len);
+	data[len] = '\0';
+	x->data = data;
+	return x;
+}
+
+void R_PreserveObject(SEXP x) {
+	x->preserved++;
+}
+
+void R_ReleaseObject(SEXP x) {
+	if (x->preserved > 0) {
+		x->preserved--;
+	}
+}
+
+int rstub_preserved(SEXP x) {
+	return x->preserved;
+}
@@ -0,0 +1,25 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rstub provides a minimal implementation of the parts of the R C
+// API used by package sexp, so that the tests of package sexp can be
+// linked and run without R. Importing rstub defines the R API symbols in
+// the importing binary; it must only be imported by tests.
+//
+// Values allocated by rstub are never freed, and there is no garbage
+// collector; protection is only counted.
+package rstub
+
+/*
+#include "rstub.h"
+*/
+import "C"
+
+import "unsafe"
+
+// Preserved returns the number of times the R value p has been preserved
+// by R_PreserveObject and not yet released by R_ReleaseObject.
+func Preserved(p unsafe.Pointer) int {
+	return int(C.rstub_preserved(C.SEXP(p)))
+}
@@ -0,0 +1,28 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include <stddef.h>
+
+typedef struct SEXPREC *SEXP;
+typedef ptrdiff_t R_xlen_t;
+typedef struct {
+	double r;
+	double i;
+} Rcomplex;
+
+// SEXPTYPE values from Rinternals.h.
+#define NILSXP 0
+#define SYMSXP 1
+#define CHARSXP 9
+#define LGLSXP 10
+#define INTSXP 13
+#define REALSXP 14
+#define CPLXSXP 15
+#define STRSXP 16
+#define VECSXP 19
+#define EXPRSXP 20
+#define RAWSXP 24
+
+SEXP STRING_ELT(SEXP x, R_xlen_t i);
+int rstub_preserved(SEXP x);
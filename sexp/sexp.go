@@ -0,0 +1,397 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sexp provides typed access to R values from Go functions wrapped
+// by rgo.
+//
+// Wrapped functions receive arbitrary R values, SEXP, as Value parameters,
+// or as unsafe.Pointer parameters converted with New, and may return them
+// in the same way. Values must only be used during the wrapped call that
+// received or allocated them, on the goroutine handling the call, unless
+// they are held by a Ref. As with the R C API, invalid operations such as
+// setting an attribute to an invalid value raise an R error.
+package sexp
+
+/*
+#include <stddef.h>
+
+// Declarations from the R API in Rinternals.h. The symbols are
+// resolved when the wrapping package is loaded into R.
+typedef struct SEXPREC *SEXP;
+typedef ptrdiff_t R_xlen_t;
+typedef struct {
+	double r;
+	double i;
+} Rcomplex;
+
+extern SEXP R_NilValue;
+extern SEXP R_NaString;
+extern double R_NaReal;
+
+int TYPEOF(SEXP x);
+R_xlen_t Rf_xlength(SEXP x);
+SEXP Rf_install(const char *name);
+SEXP Rf_getAttrib(SEXP x, SEXP name);
+SEXP Rf_setAttrib(SEXP x, SEXP name, SEXP val);
+int Rf_inherits(SEXP x, const char *class);
+SEXP Rf_allocVector(unsigned int type, R_xlen_t n);
+int *LOGICAL(SEXP x);
+int *INTEGER(SEXP x);
+double *REAL(SEXP x);
+Rcomplex *COMPLEX(SEXP x);
+unsigned char *RAW(SEXP x);
+SEXP STRING_ELT(SEXP x, R_xlen_t i);
+void SET_STRING_ELT(SEXP x, R_xlen_t i, SEXP v);
+SEXP VECTOR_ELT(SEXP x, R_xlen_t i);
+SEXP SET_VECTOR_ELT(SEXP x, R_xlen_t i, SEXP v);
+const char *Rf_translateCharUTF8(SEXP x);
+SEXP Rf_mkCharLenCE(const char *s, int len, int enc);
+int R_IsNA(double x);
+void R_PreserveObject(SEXP x);
+void R_ReleaseObject(SEXP x);
+
+// CE_UTF8 is the cetype_t value for UTF-8 encoded strings.
+#define CE_UTF8 1
+
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Type is an R SEXPTYPE.
+type Type int
+
+// R value types.
+const (
+	Nil             Type = 0  // NILSXP
+	Symbol          Type = 1  // SYMSXP
+	Pairlist        Type = 2  // LISTSXP
+	Closure         Type = 3  // CLOSXP
+	Environment     Type = 4  // ENVSXP
+	Promise         Type = 5  // PROMSXP
+	Language        Type = 6  // LANGSXP
+	Special         Type = 7  // SPECIALSXP
+	Builtin         Type = 8  // BUILTINSXP
+	Char            Type = 9  // CHARSXP
+	Logical         Type = 10 // LGLSXP
+	Integer         Type = 13 // INTSXP
+	Real            Type = 14 // REALSXP
+	Complex         Type = 15 // CPLXSXP
+	String          Type = 16 // STRSXP
+	Dots            Type = 17 // DOTSXP
+	Any             Type = 18 // ANYSXP
+	List            Type = 19 // VECSXP
+	Expression      Type = 20 // EXPRSXP
+	Bytecode        Type = 21 // BCODESXP
+	ExternalPointer Type = 22 // EXTPTRSXP
+	WeakRef         Type = 23 // WEAKREFSXP
+	Raw             Type = 24 // RAWSXP
+	S4              Type = 25 // S4SXP
+)
+
+var typeNames = map[Type]string{
+	Nil:             "NULL",
+	Symbol:          "symbol",
+	Pairlist:        "pairlist",
+	Closure:         "closure",
+	Environment:     "environment",
+	Promise:         "promise",
+	Language:        "language",
+	Special:         "special",
+	Builtin:         "builtin",
+	Char:            "char",
+	Logical:         "logical",
+	Integer:         "integer",
+	Real:            "double",
+	Complex:         "complex",
+	String:          "character",
+	Dots:            "...",
+	Any:             "any",
+	List:            "list",
+	Expression:      "expression",
+	Bytecode:        "bytecode",
+	ExternalPointer: "externalptr",
+	WeakRef:         "weakref",
+	Raw:             "raw",
+	S4:              "S4",
+}
+
+// String returns the R name of the type, as returned by typeof.
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// NA values of R logical and integer vectors.
+const (
+	NALogical = math.MinInt32
+	NAInteger = math.MinInt32
+)
+
+// NAReal returns the NA value of R double vectors.
+func NAReal() float64 {
+	return float64(C.R_NaReal)
+}
+
+// IsNA returns whether x is an R double NA, rather than any other NaN.
+func IsNA(x float64) bool {
+	return C.R_IsNA(C.double(x)) != 0
+}
+
+// Value is an R value.
+type Value struct {
+	sexp C.SEXP
+}
+
+// New returns the R value held by the unsafe.Pointer p, as passed to a
+// wrapped function.
+func New(p unsafe.Pointer) Value {
+	return Value{sexp: C.SEXP(p)}
+}
+
+// Null returns the R NULL value.
+func Null() Value {
+	return Value{sexp: C.R_NilValue}
+}
+
+// Alloc returns a new R vector of the given type and length. The type
+// must be Logical, Integer, Real, Complex, String, List or Raw.
+//
+// The returned value is not protected from the R garbage collector, so
+// any further R allocation, including by Alloc, SetString and SetAttr,
+// may free it. It must be protected with Protect or Preserve if further
+// R values are allocated before it is returned to R or stored in a
+// protected value.
+func Alloc(typ Type, n int) Value {
+	switch typ {
+	case Logical, Integer, Real, Complex, String, List, Raw:
+	default:
+		panic(fmt.Sprintf("sexp: cannot allocate %s vector", typ))
+	}
+	return Value{sexp: C.Rf_allocVector(C.uint(typ), C.R_xlen_t(n))}
+}
+
+// Pointer returns the unsafe.Pointer holding v, so that it can be
+// returned from a wrapped function.
+func (v Value) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(v.sexp)
+}
+
+// IsNull returns whether v is the R NULL value.
+func (v Value) IsNull() bool {
+	return v.sexp == C.R_NilValue
+}
+
+// Type returns the type of v.
+func (v Value) Type() Type {
+	return Type(C.TYPEOF(v.sexp))
+}
+
+// Len returns the length of v.
+func (v Value) Len() int {
+	return int(C.Rf_xlength(v.sexp))
+}
+
+// Attr returns the named attribute of v. It returns NULL if v has no
+// such attribute.
+func (v Value) Attr(name string) Value {
+	return Value{sexp: C.Rf_getAttrib(v.sexp, install(name))}
+}
+
+// SetAttr sets the named attribute of v to val. Setting an attribute
+// to NULL removes it.
+func (v Value) SetAttr(name string, val Value) {
+	C.Rf_setAttrib(v.sexp, install(name), val.sexp)
+}
+
+// Inherits returns whether class is one of the classes of v.
+func (v Value) Inherits(class string) bool {
+	cls := C.CString(class)
+	defer C.free(unsafe.Pointer(cls))
+	return C.Rf_inherits(v.sexp, cls) != 0
+}
+
+// install returns the R symbol with the given name.
+func install(name string) C.SEXP {
+	sym := C.CString(name)
+	defer C.free(unsafe.Pointer(sym))
+	return C.Rf_install(sym)
+}
+
+// check panics if v is not of type typ.
+func (v Value) check(method string, typ Type) {
+	if got := v.Type(); got != typ {
+		panic(fmt.Sprintf("sexp: %s called on %s value", method, got))
+	}
+}
+
+// Logicals returns the elements of the logical vector v. The returned
+// slice refers to the memory of v, so setting its elements sets the
+// elements of v. False is 0, true is 1 and NA is NALogical.
+func (v Value) Logicals() []int32 {
+	v.check("Logicals", Logical)
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 47]int32)(unsafe.Pointer(C.LOGICAL(v.sexp)))[:n:n]
+}
+
+// Integers returns the elements of the integer vector v. The returned
+// slice refers to the memory of v, so setting its elements sets the
+// elements of v.
+func (v Value) Integers() []int32 {
+	v.check("Integers", Integer)
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 47]int32)(unsafe.Pointer(C.INTEGER(v.sexp)))[:n:n]
+}
+
+// Reals returns the elements of the double vector v. The returned
+// slice refers to the memory of v, so setting its elements sets the
+// elements of v.
+func (v Value) Reals() []float64 {
+	v.check("Reals", Real)
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 46]float64)(unsafe.Pointer(C.REAL(v.sexp)))[:n:n]
+}
+
+// Complexes returns the elements of the complex vector v. The returned
+// slice refers to the memory of v, so setting its elements sets the
+// elements of v.
+func (v Value) Complexes() []complex128 {
+	v.check("Complexes", Complex)
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 45]complex128)(unsafe.Pointer(C.COMPLEX(v.sexp)))[:n:n]
+}
+
+// Bytes returns the elements of the raw vector v. The returned slice
+// refers to the memory of v, so setting its elements sets the elements
+// of v.
+func (v Value) Bytes() []byte {
+	v.check("Bytes", Raw)
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	return (*[1 << 48]byte)(unsafe.Pointer(C.RAW(v.sexp)))[:n:n]
+}
+
+// String returns the i'th element of the character vector v as UTF-8,
+// and whether the element is not NA.
+func (v Value) String(i int) (s string, ok bool) {
+	v.check("String", String)
+	v.checkIndex(i)
+	c := C.STRING_ELT(v.sexp, C.R_xlen_t(i))
+	if c == C.R_NaString {
+		return "", false
+	}
+	return C.GoString(C.Rf_translateCharUTF8(c)), true
+}
+
+// SetString sets the i'th element of the character vector v to s.
+func (v Value) SetString(i int, s string) {
+	v.check("SetString", String)
+	v.checkIndex(i)
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+	C.SET_STRING_ELT(v.sexp, C.R_xlen_t(i), C.Rf_mkCharLenCE(cs, C.int(len(s)), C.CE_UTF8))
+}
+
+// SetStringNA sets the i'th element of the character vector v to NA.
+func (v Value) SetStringNA(i int) {
+	v.check("SetStringNA", String)
+	v.checkIndex(i)
+	C.SET_STRING_ELT(v.sexp, C.R_xlen_t(i), C.R_NaString)
+}
+
+// Elem returns the i'th element of the list or expression vector v.
+func (v Value) Elem(i int) Value {
+	if typ := v.Type(); typ != List && typ != Expression {
+		panic(fmt.Sprintf("sexp: Elem called on %s value", typ))
+	}
+	v.checkIndex(i)
+	return Value{sexp: C.VECTOR_ELT(v.sexp, C.R_xlen_t(i))}
+}
+
+// SetElem sets the i'th element of the list or expression vector v
+// to val.
+func (v Value) SetElem(i int, val Value) {
+	if typ := v.Type(); typ != List && typ != Expression {
+		panic(fmt.Sprintf("sexp: SetElem called on %s value", typ))
+	}
+	v.checkIndex(i)
+	C.SET_VECTOR_ELT(v.sexp, C.R_xlen_t(i), val.sexp)
+}
+
+// checkIndex panics if i is out of range for v.
+func (v Value) checkIndex(i int) {
+	if n := v.Len(); i < 0 || n <= i {
+		panic(fmt.Sprintf("sexp: index %d out of range with length %d", i, n))
+	}
+}
+
+// Ref is a reference to an R value that protects the value from the R
+// garbage collector until the reference is released.
+type Ref struct {
+	v        Value
+	released bool
+}
+
+// Preserve returns a reference protecting v from the R garbage collector
+// using R_PreserveObject. The reference must be released with Release
+// when v is no longer needed.
+func Preserve(v Value) *Ref {
+	C.R_PreserveObject(v.sexp)
+	return &Ref{v: v}
+}
+
+// Protect protects v from the R garbage collector until the returned
+// release function is called, and returns v. It is intended for values
+// returned by Alloc that are used while further R values are allocated,
+// for example
+//
+//	v, release := sexp.Protect(sexp.Alloc(sexp.List, n))
+//	defer release()
+//
+// Unlike the PROTECT stack of the R C API, values may be released in any
+// order. Calling release more than once has no effect.
+func Protect(v Value) (Value, func()) {
+	r := Preserve(v)
+	return v, r.Release
+}
+
+// Value returns the referenced value. It panics if r has been released.
+func (r *Ref) Value() Value {
+	if r.released {
+		panic("sexp: use of released reference")
+	}
+	return r.v
+}
+
+// Release releases the reference using R_ReleaseObject, allowing the
+// referenced value to be garbage collected. Releasing a reference more
+// than once has no effect.
+func (r *Ref) Release() {
+	if r.released {
+		return
+	}
+	C.R_ReleaseObject(r.v.sexp)
+	r.released = true
+}
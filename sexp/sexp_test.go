@@ -0,0 +1,192 @@
+// Copyright ©2020 The rgonomic Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sexp
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rgonomic/rgo/sexp/internal/rstub"
+)
+
+var typeStringTests = []struct {
+	typ  Type
+	want string
+}{
+	{typ: Nil, want: "NULL"},
+	{typ: Logical, want: "logical"},
+	{typ: Integer, want: "integer"},
+	{typ: Real, want: "double"},
+	{typ: String, want: "character"},
+	{typ: List, want: "list"},
+	{typ: ExternalPointer, want: "externalptr"},
+	{typ: Type(99), want: "Type(99)"},
+}
+
+func TestTypeString(t *testing.T) {
+	for _, test := range typeStringTests {
+		got := test.typ.String()
+		if got != test.want {
+			t.Errorf("unexpected name for type %d: got:%q want:%q", int(test.typ), got, test.want)
+		}
+	}
+}
+
+func TestNA(t *testing.T) {
+	if !IsNA(NAReal()) {
+		t.Error("expected NAReal to be NA")
+	}
+	if !math.IsNaN(NAReal()) {
+		t.Error("expected NAReal to be NaN")
+	}
+	for _, x := range []float64{math.NaN(), 0, 1, math.Inf(1)} {
+		if IsNA(x) {
+			t.Errorf("unexpected NA for %v", x)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	if !Null().IsNull() {
+		t.Error("expected Null to be NULL")
+	}
+
+	v := Alloc(Integer, 3)
+	if v.Type() != Integer || v.Len() != 3 || v.IsNull() {
+		t.Errorf("unexpected value: type:%s len:%d", v.Type(), v.Len())
+	}
+	copy(v.Integers(), []int32{1, NAInteger, 3})
+	got := New(v.Pointer()).Integers()
+	want := []int32{1, NAInteger, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected integers: got:%v want:%v", got, want)
+	}
+	if Alloc(Real, 0).Reals() != nil {
+		t.Error("expected nil slice for empty vector")
+	}
+
+	s := Alloc(String, 2)
+	s.SetString(0, "α")
+	s.SetStringNA(1)
+	if got, ok := s.String(0); got != "α" || !ok {
+		t.Errorf("unexpected string element: got:%q,%t want:%q,true", got, ok, "α")
+	}
+	if _, ok := s.String(1); ok {
+		t.Error("expected NA string element")
+	}
+
+	cls := Alloc(String, 1)
+	cls.SetString(0, "formula")
+	v.SetAttr("class", cls)
+	if !v.Inherits("formula") || v.Inherits("data.frame") {
+		t.Error("unexpected class inheritance")
+	}
+	if v.Attr("class").Type() != String {
+		t.Error("expected class attribute")
+	}
+	v.SetAttr("class", Null())
+	if !v.Attr("class").IsNull() {
+		t.Error("expected class attribute to be removed")
+	}
+
+	l := Alloc(List, 2)
+	if !l.Elem(0).IsNull() {
+		t.Error("expected NULL list element")
+	}
+	l.SetElem(1, v)
+	if l.Elem(1).Pointer() != v.Pointer() {
+		t.Error("unexpected list element")
+	}
+}
+
+var panicTests = []struct {
+	name string
+	fn   func()
+	want string
+}{
+	{
+		name: "alloc symbol",
+		fn:   func() { Alloc(Symbol, 1) },
+		want: "sexp: cannot allocate symbol vector",
+	},
+	{
+		name: "reals of integer",
+		fn:   func() { Alloc(Integer, 1).Reals() },
+		want: "sexp: Reals called on integer value",
+	},
+	{
+		name: "string of list",
+		fn:   func() { Alloc(List, 1).String(0) },
+		want: "sexp: String called on list value",
+	},
+	{
+		name: "elem of double",
+		fn:   func() { Alloc(Real, 1).Elem(0) },
+		want: "sexp: Elem called on double value",
+	},
+	{
+		name: "string index",
+		fn:   func() { Alloc(String, 2).String(2) },
+		want: "sexp: index 2 out of range with length 2",
+	},
+	{
+		name: "negative elem index",
+		fn:   func() { Alloc(List, 1).SetElem(-1, Null()) },
+		want: "sexp: index -1 out of range with length 1",
+	},
+	{
+		name: "released reference",
+		fn: func() {
+			r := Preserve(Alloc(Raw, 1))
+			r.Release()
+			r.Value()
+		},
+		want: "sexp: use of released reference",
+	},
+}
+
+func TestPanics(t *testing.T) {
+	for _, test := range panicTests {
+		got := panics(test.fn)
+		if got != test.want {
+			t.Errorf("unexpected panic for %s: got:%q want:%q", test.name, got, test.want)
+		}
+	}
+}
+
+// panics returns the value fn panics with, or nil if it does not panic.
+func panics(fn func()) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	fn()
+	return nil
+}
+
+func TestProtect(t *testing.T) {
+	v, release := Protect(Alloc(List, 1))
+	if n := preserved(v); n != 1 {
+		t.Errorf("unexpected protection count after Protect: got:%d want:1", n)
+	}
+	release()
+	release()
+	if n := preserved(v); n != 0 {
+		t.Errorf("unexpected protection count after release: got:%d want:0", n)
+	}
+
+	r := Preserve(v)
+	if n := preserved(r.Value()); n != 1 {
+		t.Errorf("unexpected protection count after Preserve: got:%d want:1", n)
+	}
+	r.Release()
+	if n := preserved(v); n != 0 {
+		t.Errorf("unexpected protection count after Release: got:%d want:0", n)
+	}
+}
+
+func preserved(v Value) int {
+	return rstub.Preserved(v.Pointer())
+}